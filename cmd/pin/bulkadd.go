@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sp1ff/gopin/pinboard"
+	"github.com/spf13/cobra"
+)
+
+// The columns that may appear in the file given to add-bookmark --from-file;
+// tags are space-separated, as in Pinboard itself
+var bulkAddColumns = []string{"url", "title", "tags", "extended"}
+
+// addBookmarksFromFile bookmarks each row of the CSV file name (stdin, if
+// "-"), whose first line names the columns. Every bookmark also gets common's
+// tags & flags. A bad row doesn't stop the rest (unless given --fail-fast).
+func addBookmarksFromFile(cmd *cobra.Command, client *pinboard.Client, name string, common pinboard.Post, replace bool) error {
+
+	var r io.Reader = cmd.InOrStdin()
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	} else {
+		name = "<stdin>"
+	}
+
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return fmt.Errorf("%s is empty", name)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	columns := map[string]int{}
+	for i, col := range header {
+		col = strings.ToLower(strings.TrimSpace(col))
+		if !contains(bulkAddColumns, col) {
+			return fmt.Errorf("%s:1: unknown column %q; expected some of %s", name, col, strings.Join(bulkAddColumns, ","))
+		}
+		columns[col] = i
+	}
+	for _, col := range []string{"url", "title"} {
+		if _, ok := columns[col]; !ok {
+			return fmt.Errorf("%s:1: no %s column", name, col)
+		}
+	}
+	field := func(record []string, col string) string {
+		if i, ok := columns[col]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	p := startProgress(cmd, "Adding bookmarks", 0)
+	defer p.stop()
+
+	added := 0
+	var errs []error
+	for {
+		p.set(added + len(errs))
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		var line int
+		var post pinboard.Post
+		if err == nil {
+			line, _ = cr.FieldPos(0)
+			post = common
+			post.URL = field(record, "url")
+			post.Description = field(record, "title")
+			post.Extended = field(record, "extended")
+			post.Tags = append(strings.Fields(field(record, "tags")), common.Tags...)
+			err = checkBulkPost(post)
+		} else {
+			var pe *csv.ParseError
+			if errors.As(err, &pe) {
+				line = pe.Line
+			}
+		}
+		if err == nil {
+			err = addPost(cmd, client, post, replace)
+		}
+		if err != nil {
+			if failFast(cmd) {
+				return fmt.Errorf("%s:%d: %w", name, line, err)
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s:%d: %v\n", name, line, err)
+			errs = append(errs, err)
+			continue
+		}
+		added += 1
+	}
+
+	p.stop()
+	total := added + len(errs)
+	fmt.Fprintf(cmd.OutOrStdout(), "Added %d of %d bookmarks.\n", added, total)
+	if len(errs) != 0 {
+		return newBatchError(errs, "failed to add %d of %d bookmarks", len(errs), total)
+	}
+	return nil
+}
+
+// checkBulkPost catches what Pinboard would reject in a row read by
+// addBookmarksFromFile, before it's sent.
+func checkBulkPost(post pinboard.Post) error {
+
+	if post.URL == "" {
+		return errors.New("no URL")
+	}
+	if post.Description == "" {
+		return fmt.Errorf("no title for %q", post.URL)
+	}
+	return validateTags(post.Tags)
+}
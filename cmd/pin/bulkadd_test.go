@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAddBookmarksFromFile(t *testing.T) {
+
+	f := &fakePinboard{}
+	srv := newTestServer(t, f.ServeHTTP)
+	dir := t.TempDir()
+	write := func(name, text string) string {
+		name = filepath.Join(dir, name)
+		err := os.WriteFile(name, []byte(text), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return name
+	}
+
+	// The header must name both url & title; without them, every row would
+	// fail
+	tests := []struct {
+		text, err string
+	}{
+		{"url,tags\nhttps://go.dev/,go\n", "no title column"},
+		{"title,tags\nGo,go\n", "no url column"},
+		{"url,title,notes\nhttps://go.dev/,Go,x\n", `unknown column "notes"`},
+	}
+	for i, test := range tests {
+		name := write("bad.csv", test.text)
+		_, _, err := runPin(t, srv, "add-bookmark", "--from-file", name)
+		if err == nil || !strings.Contains(err.Error(), name+":1: "+test.err) {
+			t.Errorf("%d: got %v; want %q", i, err, test.err)
+		}
+	}
+	if n := len(f.calls("posts/add")); n != 0 {
+		t.Fatalf("%d bookmarks were added", n)
+	}
+
+	name := write("good.csv", "URL, Title, Tags\nhttps://go.dev/,Go,go lang\nhttps://www.gnu.org/,,gnu\n")
+	stdout, _, err := runPin(t, srv, "add-bookmark", "--from-file", name, "--tag", "imported")
+	if err == nil || err.Error() != "failed to add 1 of 2 bookmarks" {
+		t.Errorf("got %v", err)
+	}
+	if want := "Added \"https://go.dev/\".\nAdded 1 of 2 bookmarks.\n"; stdout != want {
+		t.Errorf("got %q; want %q", stdout, want)
+	}
+	if len(f.posts) != 1 || f.posts[0].Description != "Go" || strings.Join(f.posts[0].Tags, " ") != "go lang imported" {
+		t.Errorf("got %+v", f.posts)
+	}
+}
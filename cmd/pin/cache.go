@@ -0,0 +1,95 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Results are cached as JSON files beneath the user's cache directory
+// (~/.cache/gopin on Linux).
+func cacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gopin"), nil
+}
+
+// cacheKey derives a file name for a cache entry from the user (so that
+// accounts don't share entries) & any number of parts describing the query.
+func cacheKey(prefix, token string, parts ...string) string {
+	user := token[:strings.Index(token+":", ":")]
+	sum := sha256.Sum256([]byte(strings.Join(append([]string{user}, parts...), "\x00")))
+	return fmt.Sprintf("%s-%x.json", prefix, sum[:8])
+}
+
+// readCache unmarshals the cache entry name into v, returning false if there
+// is no such entry or if it's older than maxAge (zero meaning no limit).
+func readCache(name string, maxAge time.Duration, v interface{}) bool {
+
+	dir, err := cacheDir()
+	if err != nil {
+		return false
+	}
+	path := filepath.Join(dir, name)
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if maxAge > 0 && time.Since(fi.ModTime()) > maxAge {
+		log.Debug(fmt.Sprintf("Cache entry %s has expired.", path))
+		return false
+	}
+
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	if err := json.Unmarshal(buf, v); err != nil {
+		log.Warn(fmt.Sprintf("Ignoring corrupt cache entry %s: %v", path, err))
+		return false
+	}
+
+	log.Debug(fmt.Sprintf("Read %s from the cache.", path))
+	return true
+}
+
+// writeCache stores v under name; failure isn't fatal, since the cache is
+// merely an optimization.
+func writeCache(name string, v interface{}) {
+
+	dir, err := cacheDir()
+	if err == nil {
+		err = os.MkdirAll(dir, 0700)
+	}
+	var buf []byte
+	if err == nil {
+		buf, err = json.Marshal(v)
+	}
+	if err == nil {
+		err = os.WriteFile(filepath.Join(dir, name), buf, 0600)
+	}
+	if err != nil {
+		log.Warn(fmt.Sprintf("Failed to cache %s: %v", name, err))
+	}
+}
+
+// removeCache discards the cache entry name, if any.
+func removeCache(name string) {
+	dir, err := cacheDir()
+	if err != nil {
+		return
+	}
+	err = os.Remove(filepath.Join(dir, name))
+	if err != nil && !os.IsNotExist(err) {
+		log.Warn(fmt.Sprintf("Failed to remove cache entry %s: %v", name, err))
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func completion(cmd *cobra.Command, args []string) error {
+	out := cmd.OutOrStdout()
+	switch args[0] {
+	case "bash":
+		return cmd.Root().GenBashCompletionV2(out, true)
+	case "zsh":
+		return cmd.Root().GenZshCompletion(out)
+	case "fish":
+		return cmd.Root().GenFishCompletion(out, true)
+	}
+	return fmt.Errorf("unsupported shell %q", args[0])
+}
+
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish]",
+	Short: "Generate a shell completion script",
+	Long: `Generate a shell completion script. For instance, to load completions
+into the current bash session:
+
+    source <(pin completion bash)`,
+	ValidArgs:   []string{"bash", "zsh", "fish"},
+	Args:        cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	Annotations: map[string]string{annotationNoToken: "true"},
+	RunE:        completion,
+}
+
+// Tag names offered for completion are cached for this long, since the user
+// may hit TAB several times in quick succession.
+const completionCacheTTL = 5 * time.Minute
+
+func completionTagsCacheKey(cmd *cobra.Command) string {
+	return cacheKey("completion-tags", cmd.Flag("token").Value.String())
+}
+
+// isCompletionRequest returns true if cmd is cobra's hidden command for
+// computing completions.
+func isCompletionRequest(cmd *cobra.Command) bool {
+	return cmd.Name() == cobra.ShellCompRequestCmd || cmd.Name() == cobra.ShellCompNoDescRequestCmd
+}
+
+// completeTags offers the user's tags, excluding any already named in args.
+func completeTags(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+
+	// Completion requests bypass the usual setup
+	if err := resolveToken(cmd, args); err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	key := completionTagsCacheKey(cmd)
+	var names []string
+	if !readCache(key, completionCacheTTL, &names) {
+		client, err := newClient(cmd)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		tags, err := client.GetTags(ctx)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		for _, tag := range tags {
+			names = append(names, tag.Name)
+		}
+		writeCache(key, names)
+	}
+
+	given := make(map[string]bool)
+	for _, arg := range args {
+		given[arg] = true
+	}
+	var result []string
+	for _, name := range names {
+		if strings.HasPrefix(name, toComplete) && !given[name] {
+			result = append(result, name)
+		}
+	}
+	return result, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// config is the contents of ~/.pin: a series of "key = value" lines, with
+// blank lines & lines beginning with '#' ignored (i.e. a subset of TOML).
+// Lines following a "[name]" line configure the profile of that name; those
+// before the first such line (or following "[default]") configure the
+// default profile.
+type config struct {
+	Token string
+	// Format is the default for the --format flag of those commands that
+	// support it
+	Format string
+
+	name     string // of the file
+	profiles map[string]*config
+}
+
+// Environment variable consulted for the profile, absent --profile
+const profileEnvVar = "PINBOARD_PROFILE"
+
+// profile returns the configuration for the named profile. Settings it
+// doesn't make are taken from the default profile, save for the token: each
+// profile is a separate account, so it must name its own.
+func (c *config) profile(name string) (*config, error) {
+
+	if name == "" || name == "default" {
+		return c, nil
+	}
+	p, ok := c.profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("no profile %q in %s", name, c.name)
+	}
+	merged := *p
+	if merged.Format == "" {
+		merged.Format = c.Format
+	}
+	return &merged, nil
+}
+
+// selectedProfile returns the name of the profile chosen by --profile, or
+// else $PINBOARD_PROFILE; it's empty if neither is set.
+func selectedProfile(cmd *cobra.Command) string {
+	if cmd.Flags().Changed("profile") {
+		return cmd.Flag("profile").Value.String()
+	}
+	return os.Getenv(profileEnvVar)
+}
+
+// loadedConfig caches the result of getConfig.
+var loadedConfig *config
+
+// getConfig returns the configuration for the selected profile from the file
+// named by cmd's --config flag (or the default), reading it only once.
+func getConfig(cmd *cobra.Command) (*config, error) {
+
+	if loadedConfig != nil {
+		return loadedConfig, nil
+	}
+	cfg, err := loadConfig(cmd.Flag("config").Value.String())
+	if err != nil {
+		return nil, err
+	}
+	cfg, err = cfg.profile(selectedProfile(cmd))
+	if err != nil {
+		return nil, err
+	}
+	loadedConfig = cfg
+	return cfg, nil
+}
+
+func defaultConfigPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".pin"), nil
+}
+
+// loadConfig reads the configuration file at path; if path is empty, the
+// default location is used, and it is not an error for it to be missing.
+func loadConfig(path string) (*config, error) {
+
+	explicit := path != ""
+	if !explicit {
+		var err error
+		path, err = defaultConfigPath()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) && !explicit {
+			log.Debug(fmt.Sprintf("No config file at %s.", path))
+			return &config{name: path}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	log.Debug(fmt.Sprintf("Reading config file %s...", path))
+	return parseConfig(f, path)
+}
+
+func parseConfig(r io.Reader, name string) (*config, error) {
+
+	cfg := &config{name: name, profiles: make(map[string]*config)}
+	section := cfg
+	scanner := bufio.NewScanner(r)
+	for lineno := 1; scanner.Scan(); lineno += 1 {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			profile := strings.TrimSpace(line[1 : len(line)-1])
+			switch {
+			case profile == "":
+				return nil, fmt.Errorf("%s:%d: empty profile name", name, lineno)
+			case profile == "default":
+				section = cfg
+			case cfg.profiles[profile] != nil:
+				section = cfg.profiles[profile]
+			default:
+				section = &config{name: name}
+				cfg.profiles[profile] = section
+			}
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected \"key = value\"", name, lineno)
+		}
+		key := strings.TrimSpace(kv[0])
+		val, err := unquoteConfigValue(strings.TrimSpace(kv[1]))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", name, lineno, err)
+		}
+		switch key {
+		case "token":
+			section.Token = val
+		case "format":
+			section.Format = val
+		default:
+			log.Warn(fmt.Sprintf("%s:%d: unknown key %q", name, lineno, key))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func unquoteConfigValue(s string) (string, error) {
+	if len(s) >= 2 {
+		switch {
+		case s[0] == '"' && s[len(s)-1] == '"':
+			return strconv.Unquote(s)
+		case s[0] == '\'' && s[len(s)-1] == '\'':
+			return s[1 : len(s)-1], nil
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const testConfig = `# a comment
+token = "alice:0123456789ABCDEF0123"
+format = json
+
+[work]
+token = 'bob:ABCDEF0123456789ABCD'
+
+[scratch]
+format = csv
+`
+
+func TestParseConfig(t *testing.T) {
+
+	cfg, err := parseConfig(strings.NewReader(testConfig), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Token != "alice:0123456789ABCDEF0123" || cfg.Format != "json" {
+		t.Errorf("default profile: got %+v", cfg)
+	}
+
+	tests := []struct {
+		profile, token, format string
+	}{
+		{"", "alice:0123456789ABCDEF0123", "json"},
+		{"default", "alice:0123456789ABCDEF0123", "json"},
+		{"work", "bob:ABCDEF0123456789ABCD", "json"},
+		// no token of its own, so none at all
+		{"scratch", "", "csv"},
+	}
+	for _, test := range tests {
+		p, err := cfg.profile(test.profile)
+		if err != nil {
+			t.Errorf("profile %q: %v", test.profile, err)
+			continue
+		}
+		if p.Token != test.token || p.Format != test.format {
+			t.Errorf("profile %q: got %q, %q; want %q, %q", test.profile, p.Token, p.Format, test.token, test.format)
+		}
+	}
+
+	if _, err := cfg.profile("home"); err == nil {
+		t.Error("no error for a missing profile")
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+
+	for _, text := range []string{"token", "[]\n", `token = "bad\qescape"`} {
+		if _, err := parseConfig(strings.NewReader(text), "test"); err == nil {
+			t.Errorf("no error parsing %q", text)
+		}
+	}
+}
+
+// newTokenCommand returns a command with the flags consulted by resolveToken,
+// reading its configuration from a file holding testConfig.
+func newTokenCommand(t *testing.T) *cobra.Command {
+
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "pin.conf")
+	err := os.WriteFile(name, []byte(testConfig), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loadedConfig = nil
+	t.Cleanup(func() { loadedConfig = nil })
+
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().String("token", "", "")
+	cmd.Flags().String("profile", "", "")
+	cmd.Flags().String("config", name, "")
+	return cmd
+}
+
+func TestResolveToken(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		env      string
+		profile  string
+		envProf  string
+		want     string
+		errMatch string
+	}{
+		{"default", "", "", "", "alice:0123456789ABCDEF0123", ""},
+		{"environment", "carol:0000000000000000AAAA", "", "", "carol:0000000000000000AAAA", ""},
+		{"profile", "carol:0000000000000000AAAA", "work", "", "bob:ABCDEF0123456789ABCD", ""},
+		{"profile from environment", "", "", "work", "bob:ABCDEF0123456789ABCD", ""},
+		{"profile without a token", "", "scratch", "", "", `profile "scratch" has no API token`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv(tokenEnvVar, test.env)
+			t.Setenv(profileEnvVar, test.envProf)
+			cmd := newTokenCommand(t)
+			if test.profile != "" {
+				cmd.Flags().Set("profile", test.profile)
+			}
+
+			err := resolveToken(cmd, nil)
+			if test.errMatch != "" {
+				if err == nil || !strings.Contains(err.Error(), test.errMatch) {
+					t.Fatalf("got %v; want an error containing %q", err, test.errMatch)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := cmd.Flag("token").Value.String(); got != test.want {
+				t.Errorf("got %q; want %q", got, test.want)
+			}
+		})
+	}
+}
+
+// The config file's format applies unless --format is given.
+func TestConfigFormat(t *testing.T) {
+
+	name := filepath.Join(t.TempDir(), "pin.conf")
+	err := os.WriteFile(name, []byte("format = tsv\n[work]\nformat = csv\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newTestServer(t, tagsHandler(map[string]string{"go": "3"}, nil))
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "go\t3\n"},
+		{[]string{"--profile", "work"}, "name,use_count\ngo,3\n"},
+		{[]string{"--format", "tsv"}, "go\t3\n"},
+		{[]string{"--profile", "work", "--format", "tsv"}, "go\t3\n"},
+	}
+	for _, test := range tests {
+		args := append([]string{"--config", name, "get-tags", "--no-cache"}, test.args...)
+		stdout, _, err := runPin(t, srv, args...)
+		if err != nil {
+			t.Fatalf("%v: %v", test.args, err)
+		}
+		if stdout != test.want {
+			t.Errorf("%v: got %q; want %q", test.args, stdout, test.want)
+		}
+	}
+}
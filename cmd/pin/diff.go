@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/fatih/color"
+	"github.com/sp1ff/gopin/pinboard"
+	"github.com/spf13/cobra"
+)
+
+// tagChange is a difference between two snapshots of the user's tags.
+type tagChange struct {
+	// One of "added", "removed" or "changed"
+	Change string `json:"change"`
+	Name   string `json:"name"`
+	Old    uint64 `json:"old"`
+	New    uint64 `json:"new"`
+	Delta  int64  `json:"delta"`
+}
+
+// Markers for each kind of change, in tables
+var changeMarkers = map[string]string{"added": "+", "removed": "-", "changed": "~"}
+
+// diffTags returns the differences between the tags in old & new, ordered by
+// name.
+func diffTags(old, new []pinboard.Tag) []tagChange {
+
+	before := make(map[string]uint64, len(old))
+	for _, tag := range old {
+		before[tag.Name] = tag.UseCount
+	}
+
+	var changes []tagChange
+	for _, tag := range new {
+		n, ok := before[tag.Name]
+		switch {
+		case !ok:
+			changes = append(changes, tagChange{"added", tag.Name, 0, tag.UseCount, int64(tag.UseCount)})
+		case n != tag.UseCount:
+			changes = append(changes, tagChange{"changed", tag.Name, n, tag.UseCount, int64(tag.UseCount) - int64(n)})
+		}
+		delete(before, tag.Name)
+	}
+	for name, n := range before {
+		changes = append(changes, tagChange{"removed", name, n, 0, -int64(n)})
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
+
+// readTagSnapshot reads the tags saved in the file name, which may be an
+// export, the JSON output of get-tags, or a tags/get response as-is.
+func readTagSnapshot(name string) ([]pinboard.Tag, error) {
+
+	buf, err := readFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// tags/get maps each tag to a string, so a numeric "version" or a
+	// "tags" array identify the other two
+	var probe map[string]json.RawMessage
+	err = json.Unmarshal(buf, &probe)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a snapshot of your tags: %v", name, err)
+	}
+	if v, ok := probe["version"]; ok && !bytes.HasPrefix(v, []byte(`"`)) {
+		doc, err := parseExport(name, buf)
+		if err != nil {
+			return nil, err
+		}
+		return doc.Tags, nil
+	}
+	if t, ok := probe["tags"]; ok && bytes.HasPrefix(t, []byte("[")) {
+		var tags []pinboard.Tag
+		err = json.Unmarshal(t, &tags)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a snapshot of your tags: %v", name, err)
+		}
+		return tags, nil
+	}
+	tags, err := pinboard.ParseTags(buf)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a snapshot of your tags: %v", name, err)
+	}
+	return tags, nil
+}
+
+func diffTagsCommand(cmd *cobra.Command, args []string) error {
+
+	format, err := getFormat(cmd, "table", "json")
+	if err != nil {
+		return err
+	}
+
+	old, err := readTagSnapshot(args[0])
+	if err != nil {
+		return err
+	}
+	var new []pinboard.Tag
+	if len(args) > 1 {
+		new, err = readTagSnapshot(args[1])
+	} else {
+		var client *pinboard.Client
+		client, err = newClient(cmd)
+		if err == nil {
+			new, err = fetchTags(cmd, client)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	changes := diffTags(old, new)
+	if format == "json" {
+		if changes == nil {
+			changes = []tagChange{}
+		}
+		return writeJSON(cmd.OutOrStdout(), changes)
+	}
+	return writeTagChanges(cmd.OutOrStdout(), changes)
+}
+
+func writeTagChanges(w io.Writer, changes []tagChange) error {
+
+	if len(changes) == 0 {
+		_, err := fmt.Fprintln(w, "No differences.")
+		return err
+	}
+
+	counts := map[string]int{}
+	t := table{headers: []string{"", "Tag", "Old", "New", "Delta"}, right: []bool{false, false, true, true, true}}
+	for _, c := range changes {
+		t.rows = append(t.rows, []string{
+			changeMarkers[c.Change],
+			c.Name,
+			strconv.FormatUint(c.Old, 10),
+			strconv.FormatUint(c.New, 10),
+			fmt.Sprintf("%+d", c.Delta),
+		})
+		counts[c.Change] += 1
+	}
+	t.color = func(i, j int) *color.Color {
+		switch changes[i].Change {
+		case "added":
+			return color.New(color.FgGreen)
+		case "removed":
+			return color.New(color.FgRed)
+		}
+		return nil
+	}
+	err := t.write(w)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%d added, %d removed, %d changed\n", counts["added"], counts["removed"], counts["changed"])
+	return err
+}
+
+var diffTagsCmd = &cobra.Command{
+	Use:   "diff-tags OLD [NEW]",
+	Short: "Compare two snapshots of your tags",
+	Long: `Compare two snapshots of your tags, listing those added (+), removed (-) &
+those whose use counts changed (~). If NEW isn't given, OLD is compared with
+your tags as they are now.
+
+A snapshot may be a file written by "pin export", the JSON output of
+"pin get-tags", or a response to the tags/get API call as-is.`,
+	Args:        cobra.RangeArgs(1, 2),
+	RunE:        diffTagsCommand,
+	Annotations: map[string]string{annotationNoTokenArgs: "2"},
+}
+
+func init() {
+	diffTagsCmd.Flags().StringP("format", "f", "table", "Output format: table|json")
+	diffTagsCmd.Flags().Bool("no-cache", false, "Neither read nor update the local cache of your tags")
+	diffTagsCmd.Flags().Bool("refresh", false, "Re-fetch your tags even if the cached copy is current")
+}
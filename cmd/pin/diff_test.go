@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/sp1ff/gopin/pinboard"
+)
+
+// Each format of snapshot is recognised.
+func TestReadTagSnapshot(t *testing.T) {
+
+	tests := []struct {
+		name string
+		want []pinboard.Tag
+	}{
+		// a tags/get response
+		{"tags-old.json", []pinboard.Tag{{Name: "c", UseCount: 5}, {Name: "emacs", UseCount: 2}, {Name: "go", UseCount: 3}, {Name: "lisp", UseCount: 1}}},
+		// the output of get-tags --format json
+		{"tags-new.json", []pinboard.Tag{{Name: "c", UseCount: 3}, {Name: "emacs", UseCount: 2}, {Name: "go", UseCount: 4}, {Name: "rust", UseCount: 1}}},
+		// an export
+		{"export.json", []pinboard.Tag{{Name: "emacs", UseCount: 2}, {Name: "go", UseCount: 3}, {Name: "lisp", UseCount: 1}}},
+	}
+	for _, test := range tests {
+		got, err := readTagSnapshot(filepath.Join("testdata", test.name))
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		sort.Sort(alphaAsc(got))
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v; want %v", test.name, got, test.want)
+		}
+	}
+
+	name := filepath.Join(t.TempDir(), "bad.json")
+	err := os.WriteFile(name, []byte(`["go", "emacs"]`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = readTagSnapshot(name)
+	if err == nil || !strings.Contains(err.Error(), "is not a snapshot of your tags") {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestDiffTags(t *testing.T) {
+
+	old, err := readTagSnapshot(filepath.Join("testdata", "tags-old.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	new, err := readTagSnapshot(filepath.Join("testdata", "tags-new.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []tagChange{
+		{"changed", "c", 5, 3, -2},
+		{"changed", "go", 3, 4, 1},
+		{"removed", "lisp", 1, 0, -1},
+		{"added", "rust", 0, 1, 1},
+	}
+	if got := diffTags(old, new); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+	if got := diffTags(new, new); len(got) != 0 {
+		t.Errorf("got %v; want no changes", got)
+	}
+}
+
+func TestDiffTagsCommand(t *testing.T) {
+
+	old, new := filepath.Join("testdata", "tags-old.json"), filepath.Join("testdata", "tags-new.json")
+
+	stdout, _, err := runPin(t, nil, "diff-tags", old, new)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `|   | Tag  | Old | New | Delta |
++---+------+-----+-----+-------+
+| ~ | c    |   5 |   3 |    -2 |
+| ~ | go   |   3 |   4 |    +1 |
+| - | lisp |   1 |   0 |    -1 |
+| + | rust |   0 |   1 |    +1 |
++---+------+-----+-----+-------+
+1 added, 1 removed, 2 changed
+`
+	if stdout != want {
+		t.Errorf("got\n%s\nwant\n%s", stdout, want)
+	}
+
+	stdout, _, err = runPin(t, nil, "diff-tags", "--format", "json", old, new)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var changes []tagChange
+	err = json.Unmarshal([]byte(stdout), &changes)
+	if err != nil {
+		t.Fatalf("bad output %q: %v", stdout, err)
+	}
+	if len(changes) != 4 {
+		t.Errorf("got %v", changes)
+	}
+
+	stdout, _, err = runPin(t, nil, "diff-tags", "--format", "json", new, new)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stdout != "[]\n" {
+		t.Errorf("got %q; want an empty array", stdout)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/sp1ff/gopin/pinboard"
+)
+
+// Process exit codes; scripts may rely on these, so they mustn't change.
+const (
+	exitOK           = 0
+	exitFailure      = 1 // any failure not listed below
+	exitNetwork      = 2 // Pinboard couldn't be reached, or timed-out
+	exitUnauthorized = 3 // Pinboard rejected the API token
+	exitRateLimited  = 4 // Pinboard refused further requests for now
+)
+
+const exitCodeHelp = `Exit status:
+  0  success
+  1  failure (other than those below)
+  2  network error (Pinboard couldn't be reached, or the request timed-out)
+  3  authentication failure (Pinboard rejected the API token)
+  4  rate-limited (Pinboard refused further requests for now)
+
+With --print-exit-reason, a failure ends with a line on stderr naming the exit
+status, e.g. "EXIT: unauthorized (3)"; the names are failure, network,
+unauthorized & rate-limited respectively.`
+
+// Names for the exit codes, for --print-exit-reason; like the codes, these
+// mustn't change.
+var exitReasons = map[int]string{
+	exitOK:           "ok",
+	exitFailure:      "failure",
+	exitNetwork:      "network",
+	exitUnauthorized: "unauthorized",
+	exitRateLimited:  "rate-limited",
+}
+
+// exitReason renders code as the last line written given --print-exit-reason.
+func exitReason(code int) string {
+	return fmt.Sprintf("EXIT: %s (%d)", exitReasons[code], code)
+}
+
+func exitCode(err error) int {
+	var ue *url.Error
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, pinboard.ErrUnauthorized):
+		return exitUnauthorized
+	case errors.Is(err, pinboard.ErrRateLimited):
+		return exitRateLimited
+	case errors.As(err, &ue):
+		return exitNetwork
+	default:
+		return exitFailure
+	}
+}
+
+// batchError reports the failures of a command that carries on past them
+// (deleting each of several tags, say): its message summarises them, as each
+// has already been reported, but it wraps them all, so that the exit code
+// reflects their cause.
+type batchError struct {
+	msg  string
+	errs []error
+}
+
+func newBatchError(errs []error, format string, a ...interface{}) error {
+	return &batchError{msg: fmt.Sprintf(format, a...), errs: errs}
+}
+
+func (e *batchError) Error() string {
+	return e.msg
+}
+
+func (e *batchError) Unwrap() []error {
+	return e.errs
+}
+
+// describe renders err for the user, adding advice where we have some.
+func describe(err error) string {
+	if errors.Is(err, pinboard.ErrWaitTooLong) {
+		return err.Error() + "; try again later, or raise --retry-after-cap"
+	}
+	if errors.Is(err, pinboard.ErrUnauthorized) {
+		return "invalid API token; check --token, $PINBOARD_TOKEN, or ~/.pin"
+	}
+	if errors.Is(err, pinboard.ErrRateLimited) {
+		return "Pinboard is rate-limiting requests; wait a while, or raise --rate-interval, and try again"
+	}
+	return err.Error()
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/sp1ff/gopin/pinboard"
+)
+
+func TestExitCode(t *testing.T) {
+
+	netErr := &url.Error{Op: "Get", URL: "https://api.pinboard.in/v1/tags/get", Err: context.DeadlineExceeded}
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, exitOK},
+		{errors.New("oops"), exitFailure},
+		{pinboard.ErrUnauthorized, exitUnauthorized},
+		{fmt.Errorf("failed to delete %q: %w", "go", pinboard.ErrRateLimited), exitRateLimited},
+		{netErr, exitNetwork},
+		{newBatchError([]error{errors.New("oops"), pinboard.ErrUnauthorized}, "failed to delete %d of %d tags", 2, 2), exitUnauthorized},
+		{newBatchError([]error{fmt.Errorf("tags/delete: %w", netErr)}, "failed to delete %d of %d tags", 1, 3), exitNetwork},
+		{newBatchError([]error{errors.New("oops")}, "failed to delete %d of %d tags", 1, 3), exitFailure},
+	}
+	for _, test := range tests {
+		if got := exitCode(test.err); got != test.want {
+			t.Errorf("exitCode(%v) = %d; want %d", test.err, got, test.want)
+		}
+	}
+}
+
+func TestBatchErrorMessage(t *testing.T) {
+
+	err := newBatchError([]error{errors.New("oops")}, "failed to delete %d of %d tags", 1, 3)
+	if got, want := err.Error(), "failed to delete 1 of 3 tags"; got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
+
+func TestExitReason(t *testing.T) {
+
+	for code, want := range map[int]string{
+		exitFailure:      "EXIT: failure (1)",
+		exitNetwork:      "EXIT: network (2)",
+		exitUnauthorized: "EXIT: unauthorized (3)",
+		exitRateLimited:  "EXIT: rate-limited (4)",
+	} {
+		if got := exitReason(code); got != want {
+			t.Errorf("exitReason(%d) = %q; want %q", code, got, want)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sp1ff/gopin/pinboard"
+	"github.com/spf13/cobra"
+)
+
+// exportVersion identifies the layout of the document written by export;
+// bump it on any incompatible change, so that import can tell.
+const exportVersion = 1
+
+// Compressed exports are recognised by their first two bytes
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// exportDoc is a snapshot of a Pinboard account, as written by export.
+type exportDoc struct {
+	Version    int             `json:"version"`
+	ExportedAt time.Time       `json:"exported_at"`
+	User       string          `json:"user"`
+	Tags       []pinboard.Tag  `json:"tags"`
+	Posts      []pinboard.Post `json:"posts"`
+}
+
+func export(cmd *cobra.Command, args []string) error {
+
+	out, err := cmd.Flags().GetString("out")
+	if err != nil {
+		return err
+	}
+	gz, err := cmd.Flags().GetBool("gzip")
+	if err != nil {
+		return err
+	}
+	gz = gz || strings.HasSuffix(out, ".gz")
+
+	client, err := newClient(cmd)
+	if err != nil {
+		return err
+	}
+
+	token := cmd.Flag("token").Value.String()
+	doc := exportDoc{
+		Version:    exportVersion,
+		ExportedAt: time.Now().UTC().Truncate(time.Second),
+		User:       token[:strings.Index(token+":", ":")],
+	}
+
+	p := startProgress(cmd, "Retrieving tags", 0)
+	doc.Tags, err = client.GetTags(cmd.Context())
+	p.stop()
+	if err != nil {
+		return err
+	}
+	sort.Sort(alphaAsc(doc.Tags))
+	notify(cmd, "Retrieved %d tags.", len(doc.Tags))
+
+	p = startProgress(cmd, "Retrieving bookmarks", 0)
+	doc.Posts, err = client.GetAllPosts(cmd.Context(), pinboard.AllPostsOptions{})
+	p.stop()
+	if err != nil {
+		return err
+	}
+	notify(cmd, "Retrieved %d bookmarks.", len(doc.Posts))
+
+	if out == "" || out == "-" {
+		return writeExport(cmd.OutOrStdout(), &doc, gz)
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	err = writeExport(f, &doc, gz)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	notify(cmd, "Wrote %s.", out)
+	return nil
+}
+
+// writeExport writes doc to w, compressing it if gz is true.
+func writeExport(w io.Writer, doc *exportDoc, gz bool) error {
+
+	if !gz {
+		return writeJSON(w, doc)
+	}
+	zw := gzip.NewWriter(w)
+	err := writeJSON(zw, doc)
+	// Close flushes whatever's buffered, & writes the gzip trailer
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Back up all your bookmarks & tags as JSON",
+	Long: `Back up all your bookmarks & tags as a single JSON document.
+
+The document records a schema version & the time of the export alongside the
+data, and may be restored with import. With --gzip, or if the --out file is
+named *.gz, the document is compressed.`,
+	Args: cobra.NoArgs,
+	RunE: export,
+}
+
+// readExport reads & validates the export document in the file name, which
+// may be compressed.
+func readExport(name string) (*exportDoc, error) {
+
+	buf, err := readFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return parseExport(name, buf)
+}
+
+// readFile reads the file name, decompressing it if need be.
+func readFile(name string) ([]byte, error) {
+
+	buf, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(buf, gzipMagic) {
+		zr, err := gzip.NewReader(bytes.NewReader(buf))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		buf, err = io.ReadAll(zr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return buf, nil
+}
+
+// parseExport parses & validates buf, the contents of the export document
+// name.
+func parseExport(name string, buf []byte) (*exportDoc, error) {
+
+	var doc exportDoc
+	err := json.Unmarshal(buf, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a pin export: %v", name, err)
+	}
+	if doc.Version != exportVersion {
+		return nil, fmt.Errorf("%s is an export of version %d, but this version of pin reads only version %d",
+			name, doc.Version, exportVersion)
+	}
+	for i, post := range doc.Posts {
+		if post.URL == "" {
+			return nil, fmt.Errorf("%s: bookmark %d has no URL", name, i+1)
+		}
+	}
+
+	return &doc, nil
+}
+
+func importPosts(cmd *cobra.Command, args []string) error {
+
+	in, err := cmd.Flags().GetString("in")
+	if err != nil {
+		return err
+	}
+	replace, err := cmd.Flags().GetBool("replace")
+	if err != nil {
+		return err
+	}
+
+	doc, err := readExport(in)
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient(cmd)
+	if err != nil {
+		return err
+	}
+	defer invalidateTagsCache(cmd)
+
+	p := startProgress(cmd, "Adding bookmarks", len(doc.Posts))
+	added, skipped := 0, 0
+	var errs []error
+	for i, post := range doc.Posts {
+		p.set(i)
+		err = client.AddPost(cmd.Context(), post, replace)
+		if errors.Is(err, pinboard.ErrExists) {
+			skipped += 1
+			continue
+		}
+		if err != nil {
+			if failFast(cmd) {
+				return fmt.Errorf("failed to add %q: %w", post.URL, err)
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "failed to add %q: %v\n", post.URL, err)
+			errs = append(errs, err)
+			if cmd.Context().Err() != nil {
+				break
+			}
+			continue
+		}
+		added += 1
+	}
+	p.stop()
+
+	report(cmd, "Added %d, skipped %d (already bookmarked), failed %d.", added, skipped, len(errs))
+	if len(errs) != 0 {
+		return newBatchError(errs, "failed to add %d of %d bookmarks", len(errs), len(doc.Posts))
+	}
+	return nil
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Restore bookmarks from a backup made by export",
+	Long: `Restore bookmarks from a backup made by export.
+
+Bookmarks that already exist are left alone, unless --replace is given.
+Compressed backups are decompressed transparently.`,
+	Args: cobra.NoArgs,
+	RunE: importPosts,
+}
+
+func init() {
+	exportCmd.Flags().String("out", "", "Write the backup to this file (default stdout)")
+	exportCmd.Flags().Bool("gzip", false, "Compress the backup (the default if --out ends in .gz)")
+
+	importCmd.Flags().String("in", "", "The backup to restore")
+	importCmd.MarkFlagRequired("in")
+	importCmd.Flags().Bool("replace", false, "Overwrite bookmarks that already exist")
+}
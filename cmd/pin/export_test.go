@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGetTagsFromExport(t *testing.T) {
+
+	// No server: the tags must come from the file alone
+	stdout, _, err := runPin(t, nil, "get-tags", "--from-file", "testdata/export.json", "--format", "tsv", "--order", "desc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "go\t3\nemacs\t2\nlisp\t1\n"; stdout != want {
+		t.Errorf("got %q; want %q", stdout, want)
+	}
+}
+
+func TestReadExport(t *testing.T) {
+
+	doc, err := readExport("testdata/export.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.User != "user" || len(doc.Tags) != 3 || len(doc.Posts) != 2 {
+		t.Errorf("got %+v", doc)
+	}
+	if post := doc.Posts[1]; post.URL != "https://www.gnu.org/software/emacs/" || post.Shared || !post.ToRead {
+		t.Errorf("got %+v", post)
+	}
+
+	if _, err := readExport("testdata/missing.json"); err == nil {
+		t.Error("read a missing export")
+	}
+}
+
+// export reports its progress on stderr, unless asked not to.
+func TestExportQuiet(t *testing.T) {
+
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tags/get":
+			w.Write([]byte(`{"go": "1", "emacs": "1", "lisp": "1"}`))
+		case "/posts/all":
+			w.Write([]byte(`[{"href": "https://go.dev/", "description": "Go", "time": "2024-01-02T03:04:05Z", "shared": "yes", "toread": "no", "tags": "go"},
+{"href": "https://www.gnu.org/software/emacs/", "description": "Emacs", "time": "2024-01-02T03:04:05Z", "shared": "no", "toread": "yes", "tags": "emacs lisp"}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	name := filepath.Join(t.TempDir(), "backup.json")
+
+	_, stderr, err := runPin(t, srv, "export", "--out", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Retrieved 3 tags.\nRetrieved 2 bookmarks.\nWrote " + name + ".\n"; stderr != want {
+		t.Errorf("got %q; want %q", stderr, want)
+	}
+
+	_, stderr, err = runPin(t, srv, "export", "--out", name, "--quiet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stderr != "" {
+		t.Errorf("wrote %q to stderr", stderr)
+	}
+}
+
+// A compressed export restores the bookmarks it was made from.
+func TestExportImportGzip(t *testing.T) {
+
+	doc, err := readExport("testdata/export.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := &fakePinboard{posts: doc.Posts}
+	name := filepath.Join(t.TempDir(), "backup.json.gz")
+	_, _, err = runPin(t, newTestServer(t, src.ServeHTTP), "export", "--out", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf, gzipMagic) {
+		t.Fatalf("%s isn't compressed", name)
+	}
+
+	dst := &fakePinboard{}
+	stdout, _, err := runPin(t, newTestServer(t, dst.ServeHTTP), "import", "--in", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dst.posts, src.posts) {
+		t.Errorf("imported %+v; want %+v", dst.posts, src.posts)
+	}
+	if want := "Added 2, skipped 0 (already bookmarked), failed 0.\n"; stdout != want {
+		t.Errorf("got %q; want %q", stdout, want)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/sp1ff/gopin/pinboard"
+)
+
+// postField is one attribute of a bookmark that may be selected for output
+// with --fields.
+type postField struct {
+	// name is used in --fields, as the JSON key & as the CSV header
+	name string
+	// header heads the field's column in tables
+	header string
+	value  func(pinboard.Post) interface{}
+}
+
+// postFields lists all the fields, in the order in which they're written when
+// none are selected.
+var postFields = []postField{
+	{"url", "URL", func(p pinboard.Post) interface{} { return p.URL }},
+	{"description", "Description", func(p pinboard.Post) interface{} { return p.Description }},
+	{"extended", "Extended", func(p pinboard.Post) interface{} { return p.Extended }},
+	{"tags", "Tags", func(p pinboard.Post) interface{} { return p.Tags }},
+	{"time", "Time", func(p pinboard.Post) interface{} { return p.Time }},
+	{"shared", "Shared", func(p pinboard.Post) interface{} { return p.Shared }},
+	{"toread", "To Read", func(p pinboard.Post) interface{} { return p.ToRead }},
+	{"visibility", "Visibility", func(p pinboard.Post) interface{} { return visibility(p) }},
+}
+
+// The fields shown in tables by default
+const defaultTableFields = "time,description,url,tags,visibility"
+
+func visibility(p pinboard.Post) string {
+	if p.Shared {
+		return "public"
+	}
+	return "private"
+}
+
+// parsePostFields resolves spec, a comma-separated list of field names, to
+// the corresponding fields, in the order given.
+func parsePostFields(spec string) ([]postField, error) {
+
+	var fields []postField
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, f := range postFields {
+			if f.name == name {
+				fields = append(fields, f)
+				found = true
+				break
+			}
+		}
+		if !found {
+			names := make([]string, len(postFields))
+			for i, f := range postFields {
+				names[i] = f.name
+			}
+			return nil, fmt.Errorf("unknown field %q; expected one or more of %s", name, strings.Join(names, ","))
+		}
+	}
+
+	return fields, nil
+}
+
+// text renders the field of p as a string; timestamps are formatted per
+// layout, in local time.
+func (f postField) text(p pinboard.Post, layout string) string {
+	switch v := f.value(p).(type) {
+	case string:
+		return v
+	case []string:
+		return strings.Join(v, " ")
+	case bool:
+		return pinboard.YesNo(v)
+	case time.Time:
+		return v.Local().Format(layout)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func writePostsTable(w io.Writer, posts []pinboard.Post, fields []postField) error {
+	t := table{}
+	for _, f := range fields {
+		t.headers = append(t.headers, f.header)
+	}
+	for _, p := range posts {
+		row := make([]string, len(fields))
+		for i, f := range fields {
+			row[i] = f.text(p, postTimeLayout)
+		}
+		t.rows = append(t.rows, row)
+	}
+	return t.write(w)
+}
+
+// newPostStream returns functions writing bookmarks to w one at a time, as
+// they're retrieved, in format (csv or ndjson) or per tmpl, if that's not
+// nil, and finishing off the output.
+func newPostStream(w io.Writer, format string, fields []postField, tmpl *template.Template) (emit func(pinboard.Post) error, finish func() error) {
+
+	if tmpl != nil {
+		emit = func(p pinboard.Post) error {
+			return writeTemplate(w, tmpl, 1, func(int) interface{} { return p })
+		}
+		return emit, func() error { return nil }
+	}
+	if format == "ndjson" {
+		enc := json.NewEncoder(w)
+		emit = func(p pinboard.Post) error {
+			if fields == nil {
+				return enc.Encode(p)
+			}
+			return enc.Encode(postRecord{post: p, fields: fields})
+		}
+		return emit, func() error { return nil }
+	}
+
+	if fields == nil {
+		fields = postFields
+	}
+	cw := csv.NewWriter(w)
+	record := make([]string, len(fields))
+	for i, f := range fields {
+		record[i] = f.name
+	}
+	cw.Write(record)
+	emit = func(p pinboard.Post) error {
+		for i, f := range fields {
+			record[i] = f.text(p, time.RFC3339)
+		}
+		cw.Write(record)
+		cw.Flush()
+		return cw.Error()
+	}
+	finish = func() error {
+		cw.Flush()
+		return cw.Error()
+	}
+	return emit, finish
+}
+
+// postRecord is a bookmark restricted to some of its fields, rendered as a
+// JSON object whose keys are in the order of those fields.
+type postRecord struct {
+	post   pinboard.Post
+	fields []postField
+}
+
+func (r postRecord) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range r.fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		v, err := json.Marshal(f.value(r.post))
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&buf, "%q:", f.name)
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func postRecords(posts []pinboard.Post, fields []postField) []postRecord {
+	records := make([]postRecord, len(posts))
+	for i, p := range posts {
+		records[i] = postRecord{post: p, fields: fields}
+	}
+	return records
+}
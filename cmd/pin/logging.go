@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// setLogLevel applies --log-level, raised one step for each --verbose.
+func setLogLevel(cmd *cobra.Command) error {
+
+	level, err := log.ParseLevel(cmd.Flag("log-level").Value.String())
+	if err != nil {
+		return err
+	}
+	verbose, err := cmd.Flags().GetCount("verbose")
+	if err != nil {
+		return err
+	}
+	level += log.Level(verbose)
+	if level > log.TraceLevel {
+		level = log.TraceLevel
+	}
+
+	log.SetLevel(level)
+	return nil
+}
+
+// setLogFormat applies --log-format.
+func setLogFormat(cmd *cobra.Command) error {
+
+	switch format := cmd.Flag("log-format").Value.String(); format {
+	case "text":
+		log.SetFormatter(&log.TextFormatter{FullTimestamp: true})
+	case "json":
+		log.SetFormatter(&log.JSONFormatter{})
+	default:
+		return fmt.Errorf("invalid --log-format %q; expected one of text|json", format)
+	}
+	return nil
+}
+
+// redactHook scrubs the secret portion of the API token (i.e. everything
+// after the colon in "user:HEX") from every log entry, whatever its origin,
+// before it's written. The token is looked up as each entry is logged, since
+// it may not have been settled when the hook is installed.
+type redactHook struct {
+	token func() string
+}
+
+func newRedactHook(token func() string) *redactHook {
+	return &redactHook{token: token}
+}
+
+func (h *redactHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (h *redactHook) Fire(entry *log.Entry) error {
+	token := h.token()
+	secret := token[strings.LastIndex(token, ":")+1:]
+	if secret == "" {
+		return nil
+	}
+	entry.Message = strings.ReplaceAll(entry.Message, secret, "REDACTED")
+	for k, v := range entry.Data {
+		if s, ok := v.(string); ok {
+			entry.Data[k] = strings.ReplaceAll(s, secret, "REDACTED")
+		}
+	}
+	return nil
+}
+
+// setRedactHook installs a redactHook for cmd's --token in place of any left
+// by a previous run.
+func setRedactHook(cmd *cobra.Command) {
+	hooks := make(log.LevelHooks)
+	hooks.Add(newRedactHook(func() string {
+		if f := cmd.Flag("token"); f != nil {
+			return f.Value.String()
+		}
+		return ""
+	}))
+	log.StandardLogger().ReplaceHooks(hooks)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The token mustn't appear in the log, even at the most verbose level.
+func TestLogRedaction(t *testing.T) {
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	srv := newTestServer(t, tagsHandler(map[string]string{"go": "1"}, nil))
+	for _, format := range []string{"text", "json"} {
+		buf.Reset()
+		_, stderr, err := runPin(t, srv, "get-tags", "--no-cache", "--log-level", "trace", "--log-format", format)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		secret := testToken[strings.Index(testToken, ":")+1:]
+		if strings.Contains(buf.String(), secret) || strings.Contains(stderr, secret) {
+			t.Errorf("%s: the token appears in the log:\n%s%s", format, buf.String(), stderr)
+		}
+		if !strings.Contains(buf.String(), "tags/get") {
+			t.Errorf("%s: the request wasn't logged:\n%s", format, buf.String())
+		}
+	}
+}
+
+// Each run replaces the hook left by the last, rather than adding another.
+func TestRedactHookReplaced(t *testing.T) {
+
+	srv := newTestServer(t, tagsHandler(map[string]string{"go": "1"}, nil))
+	for i := 0; i < 3; i++ {
+		_, _, err := runPin(t, srv, "get-tags", "--no-cache")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, level := range log.AllLevels {
+		if n := len(log.StandardLogger().Hooks[level]); n != 1 {
+			t.Errorf("%v: got %d hooks; want 1", level, n)
+		}
+	}
+}
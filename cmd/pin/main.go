@@ -1,30 +1,35 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"encoding/csv"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"math"
-	"net/http"
 	"os"
+	"os/signal"
+	"path"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/fatih/color"
 	log "github.com/sirupsen/logrus"
+	"github.com/sp1ff/gopin/pinboard"
 	"github.com/spf13/cobra"
 )
 
-type pinboardTag struct {
-	Name     string
-	UseCount uint64
-}
-
-type alphaAsc []pinboardTag
-type alphaDsc []pinboardTag
-type useAsc []pinboardTag
-type useDsc []pinboardTag
+type alphaAsc []pinboard.Tag
+type alphaDsc []pinboard.Tag
+type alphaFoldAsc []pinboard.Tag
+type alphaFoldDsc []pinboard.Tag
+type useAsc []pinboard.Tag
+type useDsc []pinboard.Tag
+type lenAsc []pinboard.Tag
+type lenDsc []pinboard.Tag
 
 func (x alphaAsc) Len() int           { return len(x) }
 func (x alphaAsc) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
@@ -34,164 +39,1045 @@ func (x alphaDsc) Len() int           { return len(x) }
 func (x alphaDsc) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
 func (x alphaDsc) Less(i, j int) bool { return x[i].Name > x[j].Name }
 
-func (x useAsc) Len() int           { return len(x) }
-func (x useAsc) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
-func (x useAsc) Less(i, j int) bool { return x[i].UseCount < x[j].UseCount }
+// lessFold orders a before b case-insensitively, falling back to a byte-wise
+// comparison for names differing only in case, so the order is total.
+func lessFold(a, b string) bool {
+	la, lb := strings.ToLower(a), strings.ToLower(b)
+	if la != lb {
+		return la < lb
+	}
+	return a < b
+}
+
+func (x alphaFoldAsc) Len() int           { return len(x) }
+func (x alphaFoldAsc) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
+func (x alphaFoldAsc) Less(i, j int) bool { return lessFold(x[i].Name, x[j].Name) }
+
+func (x alphaFoldDsc) Len() int           { return len(x) }
+func (x alphaFoldDsc) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
+func (x alphaFoldDsc) Less(i, j int) bool { return lessFold(x[j].Name, x[i].Name) }
+
+// Tags with equal use counts are ordered by name, so that the output doesn't
+// depend on map iteration order.
+
+func (x useAsc) Len() int      { return len(x) }
+func (x useAsc) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
+func (x useAsc) Less(i, j int) bool {
+	if x[i].UseCount != x[j].UseCount {
+		return x[i].UseCount < x[j].UseCount
+	}
+	return x[i].Name < x[j].Name
+}
+
+func (x useDsc) Len() int      { return len(x) }
+func (x useDsc) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
+func (x useDsc) Less(i, j int) bool {
+	if x[i].UseCount != x[j].UseCount {
+		return x[i].UseCount > x[j].UseCount
+	}
+	return x[i].Name < x[j].Name
+}
+
+// Tag lengths are measured in characters, not bytes; again, ties are broken
+// on name.
+
+func (x lenAsc) Len() int      { return len(x) }
+func (x lenAsc) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
+func (x lenAsc) Less(i, j int) bool {
+	li, lj := utf8.RuneCountInString(x[i].Name), utf8.RuneCountInString(x[j].Name)
+	if li != lj {
+		return li < lj
+	}
+	return x[i].Name < x[j].Name
+}
+
+func (x lenDsc) Len() int      { return len(x) }
+func (x lenDsc) Swap(i, j int) { x[i], x[j] = x[j], x[i] }
+func (x lenDsc) Less(i, j int) bool {
+	li, lj := utf8.RuneCountInString(x[i].Name), utf8.RuneCountInString(x[j].Name)
+	if li != lj {
+		return li > lj
+	}
+	return x[i].Name < x[j].Name
+}
+
+// newClient returns a Pinboard client configured from cmd's flags.
+func newClient(cmd *cobra.Command) (*pinboard.Client, error) {
+
+	timeout, err := cmd.Flags().GetDuration("timeout")
+	if err != nil {
+		return nil, err
+	}
+
+	interval, err := cmd.Flags().GetDuration("rate-interval")
+	if err != nil {
+		return nil, err
+	}
+
+	retries, err := cmd.Flags().GetInt("max-retries")
+	if err != nil {
+		return nil, err
+	}
+
+	retryCap, err := cmd.Flags().GetDuration("retry-after-cap")
+	if err != nil {
+		return nil, err
+	}
+
+	retryTimeouts, err := cmd.Flags().GetBool("retry-on-timeout")
+	if err != nil {
+		return nil, err
+	}
+
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []pinboard.Option{
+		pinboard.WithDryRun(dryRun),
+		pinboard.WithUserAgent(userAgent()),
+		pinboard.WithTimeout(timeout),
+		pinboard.WithRateInterval(interval),
+		pinboard.WithMaxRetries(retries),
+		pinboard.WithRetryAfterCap(retryCap),
+		pinboard.WithRetryOnTimeout(retryTimeouts),
+	}
+	if base := cmd.Flag("api-base").Value.String(); base != "" {
+		opts = append(opts, pinboard.WithBaseURL(base))
+	}
+	insecure, err := cmd.Flags().GetBool("insecure")
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, pinboard.WithInsecure(insecure))
+	if proxy := cmd.Flag("proxy").Value.String(); proxy != "" {
+		opts = append(opts, pinboard.WithProxy(proxy))
+	}
+	if size := cmd.Flag("max-response-size").Value.String(); size != "" {
+		n, err := parseSize(size)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --max-response-size: %v", err)
+		}
+		opts = append(opts, pinboard.WithMaxResponseSize(n))
+	}
+	trace, err := cmd.Flags().GetBool("trace")
+	if err != nil {
+		return nil, err
+	}
+	if trace {
+		opts = append(opts, pinboard.WithTrace(cmd.ErrOrStderr()))
+	}
+
+	return pinboard.NewClient(cmd.Flag("token").Value.String(), opts...)
+}
 
-func (x useDsc) Len() int           { return len(x) }
-func (x useDsc) Swap(i, j int)      { x[i], x[j] = x[j], x[i] }
-func (x useDsc) Less(i, j int) bool { return x[i].UseCount > x[j].UseCount }
+// parseSize parses a number of bytes, optionally suffixed by K, M or G (for
+// KiB, MiB & GiB).
+func parseSize(s string) (int64, error) {
+
+	mult := int64(1)
+	num := strings.TrimSuffix(strings.TrimSuffix(strings.ToUpper(s), "IB"), "B")
+	switch {
+	case strings.HasSuffix(num, "K"):
+		mult = 1 << 10
+	case strings.HasSuffix(num, "M"):
+		mult = 1 << 20
+	case strings.HasSuffix(num, "G"):
+		mult = 1 << 30
+	}
+	if mult != 1 {
+		num = num[:len(num)-1]
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(num), 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%q is not a size (e.g. 8M)", s)
+	}
+	return n * mult, nil
+}
 
 func getTags(cmd *cobra.Command, args []string) error {
 
-	alpha, err := cmd.Flags().GetBool("alphabetical")
+	sortBy, desc, err := getSortOrder(cmd)
 	if err != nil {
 		return err
 	}
-	desc, err := cmd.Flags().GetBool("descending")
+	fold, err := cmd.Flags().GetBool("ignore-case")
 	if err != nil {
 		return err
 	}
-
-	url := fmt.Sprintf("https://api.pinboard.in/v1/tags/get?auth_token=%s&format=json", cmd.Flag("token").Value)
-	log.Debug(fmt.Sprintf("GET %s...", url))
-	rsp, err := http.Get(url)
+	format, err := getFormat(cmd, "table", "json", "ndjson", "csv", "tsv", "markdown", "plain")
 	if err != nil {
 		return err
 	}
-	defer rsp.Body.Close()
-	log.Debug(fmt.Sprintf("GET %s...done(%d).", url, rsp.StatusCode))
-
-	body, err := ioutil.ReadAll(rsp.Body)
+	if plain, _ := cmd.Flags().GetBool("plain"); plain {
+		format = "plain"
+	}
+	header, err := cmd.Flags().GetBool("header")
 	if err != nil {
 		return err
 	}
-
-	if rsp.StatusCode != http.StatusOK {
-		return errors.New(string(body))
+	minCount, err := cmd.Flags().GetUint64("min-count")
+	if err != nil {
+		return err
 	}
-
-	var tags map[string]string
-	err = json.Unmarshal(body, &tags)
+	prefix, err := cmd.Flags().GetString("prefix")
+	if err != nil {
+		return err
+	}
+	glob, err := cmd.Flags().GetString("glob")
+	if err != nil {
+		return err
+	}
+	if _, err := path.Match(glob, ""); err != nil {
+		return fmt.Errorf("invalid --glob %q: %v", glob, err)
+	}
+	percent, err := cmd.Flags().GetBool("percent")
+	if err != nil {
+		return err
+	}
+	limit, err := cmd.Flags().GetInt("limit")
+	if err != nil {
+		return err
+	}
+	if limit < 0 {
+		return fmt.Errorf("invalid --limit %d", limit)
+	}
+	countOnly, err := cmd.Flags().GetBool("count-only")
+	if err != nil {
+		return err
+	}
+	fromFile, err := cmd.Flags().GetString("from-file")
+	if err != nil {
+		return err
+	}
+	tmpl, err := getOutputTemplate(cmd, pinboard.Tag{})
+	if err != nil {
+		return err
+	}
+	if len(teeOutputs) != 0 && (countOnly || tmpl != nil) {
+		return errors.New("--count-only & --output-template write to a single destination, so can't be combined with --output FORMAT:DEST")
+	}
+	tree, err := cmd.Flags().GetBool("tree")
+	if err != nil {
+		return err
+	}
+	delim, err := cmd.Flags().GetString("tag-delimiter")
 	if err != nil {
 		return err
 	}
+	if tree {
+		if cmd.Flags().Changed("format") && format != "table" {
+			return errors.New("--tree may only be used with --format table")
+		}
+		format = "table"
+	}
+	if delim == "" {
+		return errors.New("--tag-delimiter may not be empty")
+	}
+	maxWidth, err := cmd.Flags().GetInt("max-width")
+	if err != nil {
+		return err
+	}
+	if maxWidth < 0 {
+		return errors.New("--max-width may not be negative")
+	}
+	// Unless told otherwise, fit tables to the terminal
+	fit := 0
+	if !cmd.Flags().Changed("max-width") {
+		fit = terminalWidth(cmd)
+	}
 
-	tagsSlice := make([]pinboardTag, len(tags))
-	idx := 0
-	maxTagLen := 0
-	maxUseCount := uint64(0)
-	for k, v := range tags {
-		if len(k) > maxTagLen {
-			maxTagLen = len(k)
+	var tagsSlice []pinboard.Tag
+	if fromFile != "" {
+		doc, err := readExport(fromFile)
+		if err != nil {
+			return err
 		}
-		uc, err := strconv.ParseUint(v, 10, 64)
+		tagsSlice = doc.Tags
+	} else {
+		client, err := newClient(cmd)
 		if err != nil {
 			return err
 		}
-		if uc > maxUseCount {
-			maxUseCount = uc
+		tagsSlice, err = fetchTags(cmd, client)
+		if err != nil {
+			return err
+		}
+	}
+
+	noTags := len(tagsSlice) == 0
+	allUses := sumUses(tagsSlice)
+	tagsSlice = filterTags(tagsSlice, func(tag pinboard.Tag) bool {
+		if tag.UseCount < minCount || !strings.HasPrefix(tag.Name, prefix) {
+			return false
+		}
+		if glob != "" {
+			ok, _ := path.Match(glob, tag.Name)
+			return ok
+		}
+		return true
+	})
+
+	if countOnly {
+		n := len(tagsSlice)
+		if limit > 0 && n > limit {
+			n = limit
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), n)
+		return nil
+	}
+
+	sortTags(tagsSlice, sortBy, desc, fold)
+
+	if limit > 0 && len(tagsSlice) > limit {
+		tagsSlice = tagsSlice[:limit]
+	}
+
+	if tmpl != nil {
+		return writeTemplate(cmd.OutOrStdout(), tmpl, len(tagsSlice), func(i int) interface{} { return tagsSlice[i] })
+	}
+
+	write := func(w io.Writer, format string, out *tagsOutput) error {
+		switch format {
+		case "json":
+			return writeTagsJSON(w, out)
+		case "ndjson":
+			return writeTagsNDJSON(w, out)
+		case "csv":
+			return writeTagsCSV(w, out)
+		case "tsv":
+			return writeTagsTSV(w, out, header)
+		case "plain":
+			return writeTagsPlain(w, out)
+		case "markdown":
+			return writeTagsMarkdown(w, out)
+		default:
+			if noTags {
+				fmt.Fprintln(w, "You have no tags yet.")
+				return nil
+			}
+			if len(tagsSlice) == 0 {
+				fmt.Fprintln(w, "No tags match.")
+				return nil
+			}
+			var err error
+			if tree && isHierarchical(tagsSlice, delim) {
+				err = writeTagsTree(w, out, delim, func(tags []pinboard.Tag) {
+					sortTags(tags, sortBy, desc, fold)
+				})
+			} else {
+				err = writeTagsTable(w, out)
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "Total: %d tags, %d uses\n", len(tagsSlice), sumUses(tagsSlice))
+			return nil
+		}
+	}
+
+	out := &tagsOutput{tags: tagsSlice, percent: percent, allUses: allUses, maxWidth: maxWidth, fit: fit}
+	if len(teeOutputs) == 0 {
+		return write(cmd.OutOrStdout(), format, out)
+	}
+	for _, t := range teeOutputs {
+		o := *out
+		if t.f != nil {
+			o.fit = 0
+		}
+		o.noColor = t.noColor
+		err = write(t.w, t.format, &o)
+		if err != nil {
+			return fmt.Errorf("%s: %v", t.name, err)
 		}
-		tagsSlice[idx] = pinboardTag{Name: k, UseCount: uc}
-		idx += 1
 	}
-	maxUseCount = uint64(math.Log10(float64(maxUseCount))) + 1
+	return nil
+}
+
+// sortTags sorts tags by sortBy (name, count or length), optionally in
+// descending order, ignoring case when sorting by name if fold is true.
+func sortTags(tags []pinboard.Tag, sortBy string, desc, fold bool) {
 
-	if alpha {
+	alpha := sortBy == "name"
+	if alpha && fold {
+		if desc {
+			sort.Sort(alphaFoldDsc(tags))
+		} else {
+			sort.Sort(alphaFoldAsc(tags))
+		}
+	} else if alpha {
 		if desc {
-			sort.Sort(alphaDsc(tagsSlice))
+			sort.Sort(alphaDsc(tags))
 		} else {
-			sort.Sort(alphaAsc(tagsSlice))
+			sort.Sort(alphaAsc(tags))
+		}
+	} else if sortBy == "length" {
+		if desc {
+			sort.Sort(lenDsc(tags))
+		} else {
+			sort.Sort(lenAsc(tags))
 		}
 	} else {
 		if desc {
-			sort.Sort(useDsc(tagsSlice))
+			sort.Sort(useDsc(tags))
 		} else {
-			sort.Sort(useAsc(tagsSlice))
+			sort.Sort(useAsc(tags))
+		}
+	}
+}
+
+// getSortOrder works out how get-tags is to sort its output, from --sort-by &
+// --order or, failing those, the deprecated --alphabetical & --descending.
+func getSortOrder(cmd *cobra.Command) (string, bool, error) {
+
+	sortBy, err := cmd.Flags().GetString("sort-by")
+	if err != nil {
+		return "", false, err
+	}
+	order, err := cmd.Flags().GetString("order")
+	if err != nil {
+		return "", false, err
+	}
+
+	if !cmd.Flags().Changed("sort-by") {
+		alpha, err := cmd.Flags().GetBool("alphabetical")
+		if err != nil {
+			return "", false, err
+		}
+		if alpha {
+			sortBy = "name"
+		}
+	}
+	if !cmd.Flags().Changed("order") {
+		desc, err := cmd.Flags().GetBool("descending")
+		if err != nil {
+			return "", false, err
+		}
+		if desc {
+			order = "desc"
+		}
+	}
+
+	switch sortBy {
+	case "name", "count", "length":
+	default:
+		return "", false, fmt.Errorf("invalid --sort-by %q; expected one of name|count|length", sortBy)
+	}
+	switch order {
+	case "asc", "desc":
+	default:
+		return "", false, fmt.Errorf("invalid --order %q; expected one of asc|desc", order)
+	}
+
+	return sortBy, order == "desc", nil
+}
+
+// tagsOutput is the result of get-tags, to be rendered in some format.
+type tagsOutput struct {
+	tags []pinboard.Tag
+	// If true, include a column giving each tag's share of allUses, the
+	// total use count across all the user's tags
+	percent bool
+	allUses uint64
+	// If non-zero, tag names are truncated to this many columns in tables
+	maxWidth int
+	// If non-zero, tables are fitted to this many columns (see table.fit)
+	fit int
+	// If true, tables are written without color
+	noColor bool
+}
+
+func (o *tagsOutput) percentOf(tag pinboard.Tag) float64 {
+	if o.allUses == 0 {
+		return 0
+	}
+	return 100 * float64(tag.UseCount) / float64(o.allUses)
+}
+
+// tagListing is the JSON rendering of get-tags' output.
+type tagListing struct {
+	Tags      []tagEntry `json:"tags"`
+	TotalTags int        `json:"total_tags"`
+	TotalUses uint64     `json:"total_uses"`
+}
+
+type tagEntry struct {
+	pinboard.Tag
+	Percent *float64 `json:"percent,omitempty"`
+}
+
+func (o *tagsOutput) entry(tag pinboard.Tag) tagEntry {
+	entry := tagEntry{Tag: tag}
+	if o.percent {
+		pct := math.Round(o.percentOf(tag)*10) / 10
+		entry.Percent = &pct
+	}
+	return entry
+}
+
+func writeTagsJSON(w io.Writer, o *tagsOutput) error {
+	listing := tagListing{
+		Tags:      make([]tagEntry, len(o.tags)),
+		TotalTags: len(o.tags),
+		TotalUses: sumUses(o.tags),
+	}
+	for i, tag := range o.tags {
+		listing.Tags[i] = o.entry(tag)
+	}
+	return writeJSON(w, listing)
+}
+
+// writeTagsNDJSON writes one tag per line, without the totals.
+func writeTagsNDJSON(w io.Writer, o *tagsOutput) error {
+	return writeNDJSON(w, len(o.tags), func(i int) interface{} { return o.entry(o.tags[i]) })
+}
+
+func sumUses(tags []pinboard.Tag) uint64 {
+	total := uint64(0)
+	for _, tag := range tags {
+		total += tag.UseCount
+	}
+	return total
+}
+
+// filterTags returns those of tags for which keep returns true; it re-uses
+// the storage of tags.
+func filterTags(tags []pinboard.Tag, keep func(pinboard.Tag) bool) []pinboard.Tag {
+	result := tags[:0]
+	for _, tag := range tags {
+		if keep(tag) {
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+func writeTagsTable(w io.Writer, o *tagsOutput) error {
+
+	maxUseCount := uint64(0)
+	for _, tag := range o.tags {
+		if tag.UseCount > maxUseCount {
+			maxUseCount = tag.UseCount
+		}
+	}
+
+	t := table{headers: []string{"Tag", "Use Count"}, right: []bool{false, true, true}, fit: o.fit, noColor: o.noColor}
+	if o.percent {
+		t.headers = append(t.headers, "%")
+	}
+	for _, tag := range o.tags {
+		row := []string{truncate(tag.Name, o.maxWidth), strconv.FormatUint(tag.UseCount, 10)}
+		if o.percent {
+			row = append(row, fmt.Sprintf("%5.1f", o.percentOf(tag)))
+		}
+		t.rows = append(t.rows, row)
+	}
+	t.color = func(i, j int) *color.Color {
+		if j != 1 {
+			return nil
+		}
+		return useColor(o.tags[i].UseCount, maxUseCount)
+	}
+
+	return t.write(w)
+}
+
+// useColor picks the color in which to show a use count of n, on a gradient
+// running from dim, for the least-used tags, to green for the most-used.
+func useColor(n, max uint64) *color.Color {
+	switch {
+	case max == 0 || 3*n < max:
+		return color.New(color.Faint)
+	case 3*n < 2*max:
+		return color.New(color.Reset)
+	default:
+		return color.New(color.FgGreen)
+	}
+}
+
+func writeTagsCSV(w io.Writer, o *tagsOutput) error {
+
+	cw := csv.NewWriter(w)
+	if o.percent {
+		cw.Write([]string{"name", "use_count", "percent"})
+	} else {
+		cw.Write([]string{"name", "use_count"})
+	}
+	for _, tag := range o.tags {
+		record := []string{tag.Name, strconv.FormatUint(tag.UseCount, 10)}
+		if o.percent {
+			record = append(record, strconv.FormatFloat(o.percentOf(tag), 'f', 1, 64))
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// writeTagsPlain writes each tag & its use count separated by a single space
+// (tags can't contain spaces), with nothing else, for ease of diffing.
+func writeTagsPlain(w io.Writer, o *tagsOutput) error {
+	for _, tag := range o.tags {
+		fmt.Fprintf(w, "%s %d", tag.Name, tag.UseCount)
+		if o.percent {
+			fmt.Fprintf(w, " %.1f", o.percentOf(tag))
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeTagsTSV(w io.Writer, o *tagsOutput, header bool) error {
+
+	// Check up-front, so as not to emit a partial listing
+	for _, tag := range o.tags {
+		if strings.ContainsAny(tag.Name, "\t\r\n") {
+			return fmt.Errorf("tag %q contains a tab or newline, and can't be written as TSV; try --format csv", tag.Name)
+		}
+	}
+
+	if header {
+		fmt.Fprint(w, "name\tuse_count")
+		if o.percent {
+			fmt.Fprint(w, "\tpercent")
 		}
+		fmt.Fprintln(w)
 	}
+	for _, tag := range o.tags {
+		fmt.Fprintf(w, "%s\t%d", tag.Name, tag.UseCount)
+		if o.percent {
+			fmt.Fprintf(w, "\t%.1f", o.percentOf(tag))
+		}
+		fmt.Fprintln(w)
+	}
+
+	return nil
+}
+
+// writeTagsMarkdown renders the tags as a GitHub-flavored Markdown table.
+func writeTagsMarkdown(w io.Writer, o *tagsOutput) error {
 
-	if maxUseCount < 9 {
-		maxUseCount = 9 // len("Use Count")
+	escape := strings.NewReplacer("|", `\|`)
+
+	if o.percent {
+		fmt.Fprintln(w, "| Tag | Use Count | % |")
+		fmt.Fprintln(w, "| --- | ---: | ---: |")
+	} else {
+		fmt.Fprintln(w, "| Tag | Use Count |")
+		fmt.Fprintln(w, "| --- | ---: |")
 	}
-	format := fmt.Sprintf("| %%-%ds | %%%dd |\n", maxTagLen, maxUseCount)
-	fmt.Printf(fmt.Sprintf("| %%-%ds | %%%ds |\n", maxTagLen, maxUseCount), "Tag", "Use Count")
-	rule := fmt.Sprintf("+%s+%s+", strings.Repeat("-", int(maxTagLen+2)), strings.Repeat("-", int(maxUseCount+2)))
-	fmt.Println(rule)
-	for i := 0; i < len(tagsSlice); i++ {
-		k := tagsSlice[i].Name
-		v := tagsSlice[i].UseCount
-		fmt.Printf(format, k, v)
+	for _, tag := range o.tags {
+		fmt.Fprintf(w, "| %s | %d |", escape.Replace(tag.Name), tag.UseCount)
+		if o.percent {
+			fmt.Fprintf(w, " %.1f |", o.percentOf(tag))
+		}
+		fmt.Fprintln(w)
 	}
-	fmt.Println(rule)
 
 	return nil
 }
 
 func renameTags(cmd *cobra.Command, args []string) error {
 
+	fromFile, err := cmd.Flags().GetString("from-file")
+	if err != nil {
+		return err
+	}
+	pattern, err := cmd.Flags().GetString("regex")
+	if err != nil {
+		return err
+	}
+	err = validateTags(args)
+	if err != nil {
+		return err
+	}
+
+	yes, err := cmd.Flags().GetBool("yes")
+	if err != nil {
+		return err
+	}
+	quiet, err := cmd.Flags().GetBool("quiet")
+	if err != nil {
+		return err
+	}
+	preview, err := cmd.Flags().GetBool("preview")
+	if err != nil {
+		return err
+	}
+	// The affected bookmarks are counted for the user's benefit, unless they
+	// don't want to know; they're needed anyway to notice merges, unless
+	// given --yes
+	show := len(args) == 2 && !quiet && (preview || isTerminal(os.Stdin))
+	fetch := len(args) != 2 || show || !yes
+
+	client, err := newClient(cmd)
+	if err != nil {
+		return err
+	}
+	defer invalidateTagsCache(cmd)
+
+	r, err := newRenamer(cmd, client, fetch)
+	if err != nil {
+		return err
+	}
+
+	if fromFile != "" {
+		return renameTagsFromFile(cmd, r, fromFile)
+	}
+	if pattern != "" {
+		return renameTagsByRegex(cmd, r, pattern)
+	}
+
 	old := args[0]
 	new := args[1]
-
-	url := fmt.Sprintf("https://api.pinboard.in/v1/tags/rename?auth_token=%s&old=%s&new=%s&format=json", cmd.Flag("token").Value, old, new)
-	log.Debug(fmt.Sprintf("GET %s...", url))
-	rsp, err := http.Get(url)
+	if fetch {
+		n, ok := r.uses[old]
+		if !ok {
+			return fmt.Errorf("no such tag %q", old)
+		}
+		if show {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Renaming %q (%s) -> %q\n", old, countBookmarks(n), new)
+		}
+	}
+	ok, err := r.rename(old, new)
 	if err != nil {
 		return err
 	}
-	defer rsp.Body.Close()
-	log.Debug(fmt.Sprintf("GET %s...done(%d).", url, rsp.StatusCode))
+	if !ok {
+		fmt.Fprintln(cmd.ErrOrStderr(), "Not renamed.")
+		return nil
+	}
+
+	report(cmd, "Renamed %q to %q.", old, new)
+	return nil
+}
+
+// countBookmarks renders "n bookmarks", in the singular where appropriate.
+func countBookmarks(n uint64) string {
+	if n == 1 {
+		return "1 bookmark"
+	}
+	return fmt.Sprintf("%d bookmarks", n)
+}
+
+func validateTags(tags []string) error {
+	for _, tag := range tags {
+		if err := pinboard.ValidateTag(tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deleteTags(cmd *cobra.Command, args []string) error {
 
-	body, err := ioutil.ReadAll(rsp.Body)
+	err := validateTags(args)
+	if err != nil {
+		return err
+	}
+	client, err := newClient(cmd)
 	if err != nil {
 		return err
 	}
 
-	if rsp.StatusCode != http.StatusOK {
-		return errors.New(string(body))
+	defer invalidateTagsCache(cmd)
+
+	return deleteTagList(cmd, client, args)
+}
+
+// deleteTagList deletes each of tags, carrying on past any failures (unless
+// given --fail-fast).
+func deleteTagList(cmd *cobra.Command, client *pinboard.Client, tags []string) error {
+
+	p := startProgress(cmd, "Deleting tags", len(tags))
+	defer p.stop()
+
+	var errs []error
+	for i, tag := range tags {
+		p.set(i)
+		err := client.DeleteTag(cmd.Context(), tag)
+		if err != nil {
+			if failFast(cmd) {
+				return fmt.Errorf("failed to delete %q: %w", tag, err)
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "Failed to delete %q: %v\n", tag, err)
+			errs = append(errs, err)
+			continue
+		}
+		report(cmd, "Deleted %q.", tag)
 	}
+	p.stop()
 
-	fmt.Printf("%v\n", body)
+	if len(errs) != 0 {
+		return newBatchError(errs, "failed to delete %d of %d tags", len(errs), len(tags))
+	}
 	return nil
 }
 
 var getTagsCmd = &cobra.Command{
 	Use:   "get-tags",
 	Short: "Retrieve all your tags along with their use counts",
-	RunE:  getTags,
+	Long: `Retrieve all your tags along with their use counts.
+
+With --from-file, the tags are read from a file written by "pin export"
+rather than retrieved from Pinboard (in which case no API token is needed).
+
+With --tree, tags such as "work/clients/acme" are shown as a tree, nested by
+the --tag-delimiter, with each level's use count the total of those beneath
+it; siblings are sorted per --sort-by & --order.
+
+Tags that tie under the sort order are ordered by name, so for a given set of
+tags & flags the output is always the same; --plain output, with no borders
+or totals, is suited to keeping under version control.
+
+To write the tags in several formats at once, give --output as FORMAT:DEST,
+repeatedly: "-o table:- -o json:tags.json" shows a table & saves JSON, from a
+single request to Pinboard.`,
+	RunE: getTags,
+	Annotations: map[string]string{
+		annotationNoTokenWith: "from-file",
+		annotationTee:         "table,json,ndjson,csv,tsv,markdown,plain",
+	},
 }
 
 var renameTagsCmd = &cobra.Command{
 	Use:   "rename-tags [old] [new]",
 	Short: "Rename a tag, or fold it into an existing tag",
-	Args:  cobra.ExactArgs(2),
-	RunE:  renameTags,
+	Long: `Rename a tag, or fold it into an existing tag.
+
+With --from-file, rename many tags, as listed in a CSV file of "old,new"
+pairs, one per line; blank lines and lines beginning with '#' are ignored.
+
+With --regex & --replace, rename every tag matching a regular expression
+by substituting the replacement (which may refer to submatches as $1, $2 &c)
+for each match; the renames are previewed, and carried out only once
+confirmed. Renames that would fold into an existing tag are skipped unless
+--allow-merge is given.
+
+If the new name is already in use, Pinboard merges the two tags; as that
+can't be undone, pin asks first (unless given --yes).`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if cmd.Flags().Changed("from-file") || cmd.Flags().Changed("regex") {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(2)(cmd, args)
+	},
+	RunE: renameTags,
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) >= 2 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completeTags(cmd, args, toComplete)
+	},
+}
+
+var deleteTagsCmd = &cobra.Command{
+	Use:               "delete-tags [tag...]",
+	Short:             "Delete one or more tags",
+	Args:              cobra.MinimumNArgs(1),
+	RunE:              deleteTags,
+	ValidArgsFunction: completeTags,
 }
 
 func init() {
+	// stdout is reserved for command output, so that it may be piped
+	// elsewhere; diagnostics all go to stderr.
 	log.SetFormatter(&log.TextFormatter{FullTimestamp: true})
-	log.SetOutput(os.Stdout)
-	log.SetLevel(log.DebugLevel)
+	log.SetOutput(os.Stderr)
+	log.SetLevel(log.WarnLevel)
 
+	renameTagsCmd.Flags().String("from-file", "", "Rename the tags listed in this CSV file")
+	renameTagsCmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation")
+	renameTagsCmd.Flags().String("regex", "", "Rename all tags matching this regular expression")
+	renameTagsCmd.Flags().String("replace", "", "Replacement for matches of --regex")
+	renameTagsCmd.Flags().Bool("preview", false, "Show how many bookmarks will be affected before renaming (the default on a terminal)")
+	renameTagsCmd.Flags().Bool("allow-merge", false, "With --regex, fold into existing tags rather than skipping them")
+	renameTagsCmd.MarkFlagsRequiredTogether("regex", "replace")
+	renameTagsCmd.MarkFlagsMutuallyExclusive("from-file", "regex")
+
+	getTagsCmd.Flags().String("sort-by", "count", "Sort by: name|count|length")
+	getTagsCmd.Flags().String("order", "asc", "Sort order: asc|desc")
 	getTagsCmd.Flags().BoolP("alphabetical", "a", false, "Sort alphabetically")
+	getTagsCmd.Flags().MarkDeprecated("alphabetical", "use --sort-by name")
 	getTagsCmd.Flags().BoolP("descending", "d", false, "Sort in descending order")
+	getTagsCmd.Flags().MarkDeprecated("descending", "use --order desc")
+	getTagsCmd.Flags().BoolP("ignore-case", "i", false, "Ignore case when sorting by name")
+	getTagsCmd.Flags().StringP("format", "f", "table", "Output format: table|json|ndjson|csv|tsv|markdown|plain")
+	getTagsCmd.Flags().Bool("plain", false, "Shorthand for --format plain: \"name use_count\" per line, for diffing")
+	getTagsCmd.MarkFlagsMutuallyExclusive("format", "plain")
+	getTagsCmd.Flags().Bool("header", false, "Include a header row in TSV output")
+	getTagsCmd.Flags().Bool("no-cache", false, "Neither read nor update the local cache of your tags")
+	getTagsCmd.Flags().Bool("refresh", false, "Re-fetch your tags even if the cached copy is current")
+	getTagsCmd.Flags().Bool("percent", false, "Show each tag's share of all tag uses")
+	getTagsCmd.Flags().Uint64("min-count", 0, "Only show tags used at least this many times")
+	getTagsCmd.Flags().String("prefix", "", "Only show tags beginning with this prefix")
+	getTagsCmd.Flags().IntP("limit", "n", 0, "Show at most this many tags, after sorting (zero for all)")
+	getTagsCmd.Flags().Bool("count-only", false, "Print only the number of tags that would be shown")
+	getTagsCmd.Flags().String("glob", "", "Only show tags matching this pattern (e.g. 'proj/*')")
+	getTagsCmd.Flags().String("output-template", "", "Write each tag per this Go template (e.g. '{{.Name}}={{.UseCount}}')")
+	getTagsCmd.MarkFlagsMutuallyExclusive("format", "output-template")
+	getTagsCmd.Flags().Bool("tree", false, "Show hierarchical tags (e.g. work/clients/acme) as a tree, with totals at each level")
+	getTagsCmd.Flags().Int("max-width", 0, "In tables, truncate tag names to this many columns (zero for no limit; by default, tables are fitted to the terminal)")
+	getTagsCmd.Flags().String("tag-delimiter", "/", "The separator between levels of hierarchical tags, for --tree")
+	getTagsCmd.Flags().String("from-file", "", "Read the tags from this file, written by export, rather than from Pinboard")
+	getTagsCmd.MarkFlagsMutuallyExclusive("from-file", "no-cache")
+	getTagsCmd.MarkFlagsMutuallyExclusive("from-file", "refresh")
 }
 
-func main() {
+// Commands carrying this annotation may be run without an API token
+const annotationNoToken = "noToken"
+
+// Commands carrying this annotation may be run without an API token when
+// given the flag named by its value
+const annotationNoTokenWith = "noTokenWith"
+
+// Commands carrying this annotation may be run without an API token when
+// given the number of arguments given by its value
+const annotationNoTokenArgs = "noTokenArgs"
+
+// Environment variable consulted for the API token
+const tokenEnvVar = "PINBOARD_TOKEN"
+
+func setup(cmd *cobra.Command, args []string) error {
+	setRedactHook(cmd)
+	if isCompletionRequest(cmd) {
+		return nil
+	}
+	err := setLogLevel(cmd)
+	if err != nil {
+		return err
+	}
+	err = setLogFormat(cmd)
+	if err != nil {
+		return err
+	}
+	err = openOutput(cmd)
+	if err != nil {
+		return err
+	}
+	err = setColor(cmd)
+	if err != nil {
+		return err
+	}
+	err = setQuery(cmd)
+	if err != nil {
+		return err
+	}
+	return resolveToken(cmd, args)
+}
+
+// resolveToken settles on the API token to be used: --token, if given, else
+// the token for the profile named by --profile or $PINBOARD_PROFILE, if any,
+// else $PINBOARD_TOKEN, else the token for the config file's default profile.
+func resolveToken(cmd *cobra.Command, args []string) error {
+
+	if _, ok := cmd.Annotations[annotationNoToken]; ok {
+		return nil
+	}
+	if flag, ok := cmd.Annotations[annotationNoTokenWith]; ok && cmd.Flags().Changed(flag) {
+		return nil
+	}
+	if n, ok := cmd.Annotations[annotationNoTokenArgs]; ok && n == strconv.Itoa(len(args)) {
+		return nil
+	}
+	if cmd.Flags().Changed("token") {
+		if cmd.Flag("token").Value.String() != "-" {
+			return nil
+		}
+		if isCompletionRequest(cmd) {
+			return errors.New("can't read the API token from stdin while completing")
+		}
+		tok, err := readToken(cmd)
+		if err != nil {
+			return err
+		}
+		return cmd.Flags().Set("token", tok)
+	}
+	// naming a profile is a request for its token in particular
+	profile := selectedProfile(cmd)
+	if tok := os.Getenv(tokenEnvVar); tok != "" && profile == "" {
+		log.Debug(fmt.Sprintf("Using the API token from $%s.", tokenEnvVar))
+		return cmd.Flags().Set("token", tok)
+	}
+
+	cfg, err := getConfig(cmd)
+	if err != nil {
+		return err
+	}
+	if cfg.Token == "" && profile != "" {
+		return fmt.Errorf("profile %q has no API token", profile)
+	}
+	if cfg.Token == "" {
+		return fmt.Errorf(`no API token found; either:
+    - pass --token,
+    - set $%s, or
+    - add a line "token = user:HEX" to ~/.pin (or the file named by --config)`, tokenEnvVar)
+	}
+	return cmd.Flags().Set("token", cfg.Token)
+}
+
+// newRootCmd assembles the pin command, with all its sub-commands.
+func newRootCmd() *cobra.Command {
 
-	// TODO(sp1ff): Add --version flag
 	var rootCmd = &cobra.Command{
-		Use:           "app",
-		SilenceUsage:  true,
-		SilenceErrors: true,
-	}
-	// TODO(sp1ff): Come up with other ways to specify (~/.pin, environment, e.g.)
-	rootCmd.PersistentFlags().StringP("token", "t", "", "Your pinboard.in API token (required)")
-	rootCmd.MarkFlagRequired("token")
-	rootCmd.AddCommand(getTagsCmd, renameTagsCmd)
-
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		Use:               "pin",
+		Short:             "A command-line client for pinboard.in",
+		Long:              "A command-line client for pinboard.in.\n\n" + exitCodeHelp,
+		Version:           versionString(),
+		SilenceUsage:      true,
+		SilenceErrors:     true,
+		PersistentPreRunE: setup,
+	}
+	rootCmd.SetVersionTemplate("pin {{.Version}}\n")
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.PersistentFlags().StringP("token", "t", "", "Your pinboard.in API token, or - to read it from stdin (overrides $PINBOARD_TOKEN & ~/.pin)")
+	rootCmd.PersistentFlags().StringP("config", "c", "", "Configuration file (default ~/.pin)")
+	rootCmd.PersistentFlags().String("profile", "", "Use this profile from the configuration file (overrides $PINBOARD_PROFILE)")
+	rootCmd.PersistentFlags().String("log-level", "warn", "Log level: panic|fatal|error|warn|info|debug|trace")
+	rootCmd.PersistentFlags().String("log-format", "text", "Log format: text|json")
+	rootCmd.PersistentFlags().CountP("verbose", "v", "Increase verbosity (may be repeated)")
+	rootCmd.PersistentFlags().Bool("dry-run", false, "Show the changes that would be made, without making them")
+	rootCmd.PersistentFlags().Bool("fail-fast", false, "Stop batch commands (e.g. delete-tags, import) at the first failure, rather than carrying on")
+	rootCmd.PersistentFlags().BoolP("quiet", "q", false, "Don't show progress, or notes on it, while slow commands run")
+	rootCmd.PersistentFlags().StringArrayP("output", "o", nil, "Write results to this file rather than stdout; for get-tags, may be repeated as FORMAT:DEST (e.g. json:tags.json, table:-)")
+	rootCmd.PersistentFlags().String("color", "auto", "Color table output: auto|always|never")
+	rootCmd.PersistentFlags().String("query", "", "Print only what this GJSON path (e.g. 'tags.#(use_count>10)#.name') selects from JSON output (given several --output destinations, it applies to the json ones alone)")
+	rootCmd.PersistentFlags().Duration("timeout", 30*time.Second, "Time limit on each request to Pinboard (zero for none)")
+	rootCmd.PersistentFlags().Duration("rate-interval", 3*time.Second, "Minimum time between requests to Pinboard")
+	rootCmd.PersistentFlags().String("max-response-size", "", "Refuse responses larger than this (e.g. 16M; default 8M, or 512M for get-bookmarks & export)")
+	rootCmd.PersistentFlags().Int("max-retries", 3, "Number of times (at most 10) to retry requests rejected with a 429 or 5xx status")
+	rootCmd.PersistentFlags().Bool("retry-on-timeout", false, "Retry requests that time out, too (per --max-retries)")
+	rootCmd.PersistentFlags().Duration("retry-after-cap", time.Minute, "Wait at most this long before retrying; fail if Pinboard asks for longer (zero for no limit)")
+	rootCmd.PersistentFlags().String("proxy", "", "Send requests through this HTTP(S) proxy (default per $HTTPS_PROXY &c)")
+	rootCmd.PersistentFlags().String("api-base", "", "Base URL of the Pinboard API (default https://api.pinboard.in/v1/)")
+	rootCmd.PersistentFlags().MarkHidden("api-base")
+	rootCmd.PersistentFlags().Bool("trace", false, "Write each request & response in full to stderr (with the token redacted)")
+	rootCmd.PersistentFlags().Bool("print-exit-reason", false, "On failure, finish with a line naming the exit status (e.g. \"EXIT: unauthorized (3)\")")
+	rootCmd.PersistentFlags().Bool("insecure", false, "Permit a plain-HTTP --api-base, and skip TLS certificate verification (for testing only)")
+	rootCmd.AddCommand(getTagsCmd, renameTagsCmd, mergeTagsCmd, deleteTagsCmd, findUnusedCmd, diffTagsCmd, getBookmarksCmd, recentCmd, addBookmarkCmd, deleteBookmarkCmd, markReadCmd, suggestTagsCmd, datesCmd, lastUpdateCmd, statsCmd, notesCmd, whoamiCmd, tuiCmd, exportCmd, importCmd, completionCmd, versionCmd)
+	return rootCmd
+}
+
+func main() {
+
+	rootCmd := newRootCmd()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := rootCmd.ExecuteContext(ctx)
+	if cerr := closeOutput(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, describe(err))
+		code := exitCode(err)
+		if printReason, _ := rootCmd.PersistentFlags().GetBool("print-exit-reason"); printReason {
+			fmt.Fprintln(os.Stderr, exitReason(code))
+		}
+		os.Exit(code)
 	}
 }
@@ -0,0 +1,522 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/sp1ff/gopin/pinboard"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const testToken = "user:0123456789ABCDEF0123"
+
+var (
+	testRoot     *cobra.Command
+	testRootOnce sync.Once
+)
+
+// newTestServer stands in for Pinboard, answering each request with handler.
+func newTestServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// tagsHandler answers tags/get with tags (& posts/update, which precedes it
+// unless given --no-cache), counting the calls to tags/get in calls, if
+// non-nil.
+func tagsHandler(tags map[string]string, calls *int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/posts/update":
+			w.Write([]byte(`{"update_time":"2024-01-02T03:04:05Z"}`))
+		case "/tags/get":
+			if calls != nil {
+				*calls += 1
+			}
+			json.NewEncoder(w).Encode(tags)
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+// runPin runs pin with args, directing its requests to srv (if non-nil),
+// and returns what it wrote to stdout & stderr. The configuration file, cache
+// & environment are isolated from the user's.
+func runPin(t *testing.T, srv *httptest.Server, args ...string) (string, string, error) {
+
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	err := runPinTo(t, srv, &stdout, &stderr, args...)
+	return stdout.String(), stderr.String(), err
+}
+
+// runPinTo is like runPin, but writes pin's output to stdout & stderr.
+func runPinTo(t *testing.T, srv *httptest.Server, stdout, stderr io.Writer, args ...string) error {
+
+	t.Helper()
+	testRootOnce.Do(func() { testRoot = newRootCmd() })
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CACHE_HOME", home)
+	t.Setenv(tokenEnvVar, "")
+	t.Setenv(profileEnvVar, "")
+	if srv != nil {
+		args = append([]string{"--api-base", srv.URL, "--insecure", "--rate-interval", "0", "--token", testToken}, args...)
+	}
+	noColor := color.NoColor
+	defer func() {
+		closeOutput()
+		resetCommands(testRoot)
+		loadedConfig, outputFile, teeOutputs, outputQuery = nil, nil, nil, ""
+		color.NoColor = noColor
+	}()
+
+	testRoot.SetOut(stdout)
+	testRoot.SetErr(stderr)
+	testRoot.SetArgs(args)
+	return testRoot.ExecuteContext(context.Background())
+}
+
+// resetCommands restores cmd & its sub-commands to their state before being
+// run: cobra would otherwise carry over flags from one run to the next.
+func resetCommands(cmd *cobra.Command) {
+
+	reset := func(f *pflag.Flag) {
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			sv.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	cmd.SetOut(nil)
+	cmd.SetErr(nil)
+	cmd.SetIn(nil)
+	for _, c := range cmd.Commands() {
+		resetCommands(c)
+	}
+}
+
+func TestGetTagsJSON(t *testing.T) {
+
+	srv := newTestServer(t, tagsHandler(map[string]string{"go": "3", "emacs": "7", "rust": "1", "lisp": "3"}, nil))
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{nil, []string{"rust", "go", "lisp", "emacs"}},
+		{[]string{"--order", "desc"}, []string{"emacs", "go", "lisp", "rust"}},
+		{[]string{"--sort-by", "name"}, []string{"emacs", "go", "lisp", "rust"}},
+	}
+	for _, test := range tests {
+		args := append([]string{"get-tags", "--no-cache", "--format", "json"}, test.args...)
+		stdout, _, err := runPin(t, srv, args...)
+		if err != nil {
+			t.Fatalf("%v: %v", test.args, err)
+		}
+
+		var listing tagListing
+		err = json.Unmarshal([]byte(stdout), &listing)
+		if err != nil {
+			t.Fatalf("%v: %v\n%s", test.args, err, stdout)
+		}
+		var got []string
+		for _, tag := range listing.Tags {
+			got = append(got, tag.Name)
+		}
+		if !equalStrings(got, test.want) {
+			t.Errorf("%v: got %v; want %v", test.args, got, test.want)
+		}
+		if listing.TotalTags != 4 || listing.TotalUses != 14 {
+			t.Errorf("%v: got totals %d, %d; want 4, 14", test.args, listing.TotalTags, listing.TotalUses)
+		}
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGetTagsMinCount(t *testing.T) {
+
+	srv := newTestServer(t, tagsHandler(map[string]string{"go": "3", "emacs": "7", "rust": "1", "lisp": "2"}, nil))
+
+	tests := []struct {
+		min  string
+		want string
+	}{
+		{"0", "rust\t1\nlisp\t2\ngo\t3\nemacs\t7\n"},
+		{"3", "go\t3\nemacs\t7\n"},
+		{"8", ""},
+	}
+	for _, test := range tests {
+		stdout, _, err := runPin(t, srv, "get-tags", "--no-cache", "--format", "tsv", "--min-count", test.min)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stdout != test.want {
+			t.Errorf("--min-count %s: got %q; want %q", test.min, stdout, test.want)
+		}
+	}
+}
+
+// tagsOf builds a list of tags from name/use-count pairs.
+func tagsOf(pairs ...interface{}) []pinboard.Tag {
+	var tags []pinboard.Tag
+	for i := 0; i < len(pairs); i += 2 {
+		tags = append(tags, pinboard.Tag{Name: pairs[i].(string), UseCount: uint64(pairs[i+1].(int))})
+	}
+	return tags
+}
+
+func tagNames(tags []pinboard.Tag) []string {
+	var names []string
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+func TestSortTagsIgnoreCase(t *testing.T) {
+
+	tests := []struct {
+		desc, fold bool
+		want       []string
+	}{
+		{false, false, []string{"Emacs", "Go", "emacs", "go", "rust"}},
+		{false, true, []string{"Emacs", "emacs", "Go", "go", "rust"}},
+		{true, true, []string{"rust", "go", "Go", "emacs", "Emacs"}},
+	}
+	for _, test := range tests {
+		tags := tagsOf("go", 1, "rust", 2, "Emacs", 3, "emacs", 4, "Go", 5)
+		sortTags(tags, "name", test.desc, test.fold)
+		if got := tagNames(tags); !equalStrings(got, test.want) {
+			t.Errorf("desc=%v, fold=%v: got %v; want %v", test.desc, test.fold, got, test.want)
+		}
+	}
+}
+
+// However the tags arrive, ties must be broken the same way.
+func TestSortTagsStable(t *testing.T) {
+
+	for _, sortBy := range []string{"name", "count", "length"} {
+		for _, desc := range []bool{false, true} {
+			var first []string
+			for i := 0; i < 20; i++ {
+				tags := tagsOf("go", 3, "lisp", 3, "c", 3, "emacs", 7, "rust", 1, "java", 1, "ruby", 3)
+				rand.Shuffle(len(tags), func(i, j int) { tags[i], tags[j] = tags[j], tags[i] })
+				sortTags(tags, sortBy, desc, false)
+				got := tagNames(tags)
+				if first == nil {
+					first = got
+				} else if !equalStrings(got, first) {
+					t.Fatalf("--sort-by %s, desc=%v: got %v, then %v", sortBy, desc, first, got)
+				}
+			}
+		}
+	}
+
+	tags := tagsOf("lisp", 3, "go", 3, "ruby", 3, "c", 3)
+	sortTags(tags, "count", true, false)
+	if got, want := tagNames(tags), []string{"c", "go", "lisp", "ruby"}; !equalStrings(got, want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+}
+
+// newTestClient returns a client sending its requests to srv.
+func newTestClient(t *testing.T, srv *httptest.Server) *pinboard.Client {
+
+	t.Helper()
+	client, err := pinboard.NewClient(testToken, pinboard.WithBaseURL(srv.URL), pinboard.WithInsecure(true), pinboard.WithRateInterval(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestDeleteTagsDryRun(t *testing.T) {
+
+	requests := 0
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests += 1
+		w.Write([]byte(`{"result":"done"}`))
+	})
+
+	stdout, _, err := runPin(t, srv, "--dry-run", "delete-tags", "go", "emacs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 0 {
+		t.Errorf("%d requests were sent", requests)
+	}
+	if want := "(dry run) Deleted \"go\".\n(dry run) Deleted \"emacs\".\n"; stdout != want {
+		t.Errorf("got %q; want %q", stdout, want)
+	}
+}
+
+func TestGetTagsNone(t *testing.T) {
+
+	srv := newTestServer(t, tagsHandler(map[string]string{}, nil))
+
+	stdout, _, err := runPin(t, srv, "get-tags", "--no-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stdout != "You have no tags yet.\n" {
+		t.Errorf("got %q", stdout)
+	}
+
+	stdout, _, err = runPin(t, srv, "get-tags", "--no-cache", "--format", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var listing map[string]interface{}
+	err = json.Unmarshal([]byte(stdout), &listing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tags, ok := listing["tags"].([]interface{}); !ok || len(tags) != 0 {
+		t.Errorf("got %q; want an empty list of tags", stdout)
+	}
+
+	srv = newTestServer(t, tagsHandler(map[string]string{"go": "1"}, nil))
+	stdout, _, err = runPin(t, srv, "get-tags", "--no-cache", "--min-count", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stdout != "No tags match.\n" {
+		t.Errorf("got %q", stdout)
+	}
+}
+
+// The use-count column is as wide as its header, or its longest count.
+func TestTagsTableCountWidth(t *testing.T) {
+
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	tests := []struct {
+		count uint64
+		width int
+	}{
+		{0, 9},
+		{9, 9},
+		{10, 9},
+		{1000, 9},
+		{12345678901, 11},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		err := writeTagsTable(&buf, &tagsOutput{tags: []pinboard.Tag{{Name: "go", UseCount: test.count}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		if len(lines) != 4 {
+			t.Fatalf("%d: got %q", test.count, buf.String())
+		}
+		if want := fmt.Sprintf("+-----+%s+", strings.Repeat("-", test.width+2)); lines[1] != want {
+			t.Errorf("%d: got rule %q; want %q", test.count, lines[1], want)
+		}
+		if want := fmt.Sprintf("| go  | %*d |", test.width, test.count); lines[2] != want {
+			t.Errorf("%d: got row %q; want %q", test.count, lines[2], want)
+		}
+	}
+}
+
+// fakePinboard stands in for Pinboard, holding bookmarks in memory.
+type fakePinboard struct {
+	mu    sync.Mutex
+	posts []pinboard.Post
+	// The query string of each request, by method (e.g. "posts/add")
+	requests map[string][]url.Values
+}
+
+func (f *fakePinboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	method := strings.TrimPrefix(r.URL.Path, "/")
+	q := r.URL.Query()
+	if f.requests == nil {
+		f.requests = make(map[string][]url.Values)
+	}
+	f.requests[method] = append(f.requests[method], q)
+
+	switch method {
+	case "posts/update":
+		fmt.Fprint(w, `{"update_time":"2024-01-02T03:04:05Z"}`)
+	case "posts/all":
+		json.NewEncoder(w).Encode(f.wirePosts(f.posts))
+	case "posts/get":
+		var found []pinboard.Post
+		for _, post := range f.posts {
+			if post.URL == q.Get("url") {
+				found = append(found, post)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"user": "user", "posts": f.wirePosts(found)})
+	case "posts/add":
+		post := pinboard.Post{
+			URL:         q.Get("url"),
+			Description: q.Get("description"),
+			Extended:    q.Get("extended"),
+			Tags:        strings.Fields(q.Get("tags")),
+			Shared:      q.Get("shared") != "no",
+			ToRead:      q.Get("toread") == "yes",
+		}
+		post.Time, _ = time.Parse(time.RFC3339, q.Get("dt"))
+		for i := range f.posts {
+			if f.posts[i].URL == post.URL {
+				if q.Get("replace") == "no" {
+					fmt.Fprint(w, `{"result_code":"item already exists"}`)
+					return
+				}
+				f.posts[i] = post
+				fmt.Fprint(w, `{"result_code":"done"}`)
+				return
+			}
+		}
+		f.posts = append(f.posts, post)
+		fmt.Fprint(w, `{"result_code":"done"}`)
+	case "tags/get":
+		tags := map[string]string{}
+		for _, post := range f.posts {
+			for _, tag := range post.Tags {
+				n, _ := strconv.Atoi(tags[tag])
+				tags[tag] = strconv.Itoa(n + 1)
+			}
+		}
+		json.NewEncoder(w).Encode(tags)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// wirePosts renders posts as Pinboard does.
+func (f *fakePinboard) wirePosts(posts []pinboard.Post) []map[string]string {
+	result := []map[string]string{}
+	for _, post := range posts {
+		result = append(result, map[string]string{
+			"href":        post.URL,
+			"description": post.Description,
+			"extended":    post.Extended,
+			"tags":        strings.Join(post.Tags, " "),
+			"time":        post.Time.UTC().Format(time.RFC3339),
+			"shared":      pinboard.YesNo(post.Shared),
+			"toread":      pinboard.YesNo(post.ToRead),
+		})
+	}
+	return result
+}
+
+// calls returns the query strings of the requests made for method.
+func (f *fakePinboard) calls(method string) []url.Values {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[method]
+}
+
+// The plain format is the same from one run to the next, however Pinboard
+// happens to order the tags.
+func TestGetTagsPlainDeterministic(t *testing.T) {
+
+	bodies := []string{
+		`{"go": "3", "emacs": "3", "lisp": "1", "c": "3"}`,
+		`{"c": "3", "lisp": "1", "emacs": "3", "go": "3"}`,
+	}
+	requests := 0
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(bodies[requests%len(bodies)]))
+		requests += 1
+	})
+
+	var outputs []string
+	for i := 0; i < 2; i++ {
+		stdout, _, err := runPin(t, srv, "get-tags", "--no-cache", "--plain")
+		if err != nil {
+			t.Fatal(err)
+		}
+		outputs = append(outputs, stdout)
+	}
+	if outputs[0] != outputs[1] {
+		t.Errorf("the output changed from\n%s\nto\n%s", outputs[0], outputs[1])
+	}
+	if want := "lisp 1\nc 3\nemacs 3\ngo 3\n"; outputs[0] != want {
+		t.Errorf("got %q; want %q", outputs[0], want)
+	}
+}
+
+func TestDeleteTagsFailFast(t *testing.T) {
+
+	var deleted []string
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		tag := r.URL.Query().Get("tag")
+		deleted = append(deleted, tag)
+		if tag == "emacs" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"result":"done"}`))
+	})
+
+	tests := []struct {
+		failFast bool
+		want     []string
+		err      string
+	}{
+		// by default, carry on past the failure
+		{false, []string{"go", "emacs", "lisp"}, "failed to delete 1 of 3 tags"},
+		{true, []string{"go", "emacs"}, `failed to delete "emacs": pinboard: 401 Unauthorized`},
+	}
+	for _, test := range tests {
+		deleted = nil
+		args := []string{"delete-tags", "go", "emacs", "lisp"}
+		if test.failFast {
+			args = append(args, "--fail-fast")
+		}
+		_, stderr, err := runPin(t, srv, args...)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("fail-fast=%v: got %v; want %q", test.failFast, err, test.err)
+		}
+		if exitCode(err) != exitUnauthorized {
+			t.Errorf("fail-fast=%v: got exit code %d; want %d", test.failFast, exitCode(err), exitUnauthorized)
+		}
+		if !equalStrings(deleted, test.want) {
+			t.Errorf("fail-fast=%v: deleted %v; want %v", test.failFast, deleted, test.want)
+		}
+		if !test.failFast && !strings.Contains(stderr, `Failed to delete "emacs"`) {
+			t.Errorf("the failure wasn't reported: %q", stderr)
+		}
+	}
+}
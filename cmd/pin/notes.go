@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/sp1ff/gopin/pinboard"
+	"github.com/spf13/cobra"
+)
+
+func listNotes(cmd *cobra.Command, args []string) error {
+
+	format, err := getFormat(cmd, "table", "json", "ndjson")
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient(cmd)
+	if err != nil {
+		return err
+	}
+	notes, err := client.ListNotes(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "json":
+		return writeJSON(cmd.OutOrStdout(), notes)
+	case "ndjson":
+		return writeNDJSON(cmd.OutOrStdout(), len(notes), func(i int) interface{} { return notes[i] })
+	}
+
+	t := table{
+		headers: []string{"ID", "Title", "Length", "Updated"},
+		right:   []bool{false, false, true, false},
+	}
+	for _, n := range notes {
+		t.rows = append(t.rows, []string{
+			n.ID,
+			n.Title,
+			strconv.FormatUint(n.Length, 10),
+			n.Updated.Local().Format(postTimeLayout),
+		})
+	}
+	return t.write(cmd.OutOrStdout())
+}
+
+func getNote(cmd *cobra.Command, args []string) error {
+
+	client, err := newClient(cmd)
+	if err != nil {
+		return err
+	}
+	note, err := client.GetNote(cmd.Context(), args[0])
+	if errors.Is(err, pinboard.ErrNotFound) {
+		return fmt.Errorf("no note with id %q", args[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), note.Text)
+	return nil
+}
+
+var notesCmd = &cobra.Command{
+	Use:   "notes",
+	Short: "Work with your notes",
+}
+
+var listNotesCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List your notes",
+	Args:  cobra.NoArgs,
+	RunE:  listNotes,
+}
+
+var getNoteCmd = &cobra.Command{
+	Use:   "get [id]",
+	Short: "Print the text of a note",
+	Args:  cobra.ExactArgs(1),
+	RunE:  getNote,
+}
+
+func init() {
+	listNotesCmd.Flags().StringP("format", "f", "table", "Output format: table|json|ndjson")
+	notesCmd.AddCommand(listNotesCmd, getNoteCmd)
+}
@@ -0,0 +1,467 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/fatih/color"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"golang.org/x/text/width"
+)
+
+// getFormat returns the value of cmd's --format flag, checking that it's one
+// of allowed. If the flag wasn't given, the config file's "format" is used
+// instead, so long as cmd supports it.
+func getFormat(cmd *cobra.Command, allowed ...string) (string, error) {
+
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		return "", err
+	}
+	if !cmd.Flags().Changed("format") {
+		cfg, err := getConfig(cmd)
+		if err != nil {
+			return "", err
+		}
+		if cfg.Format != "" {
+			if contains(allowed, cfg.Format) {
+				format = cfg.Format
+			} else {
+				log.Debug(fmt.Sprintf("pin %s doesn't support the configured format %q; using %q.",
+					cmd.Name(), cfg.Format, format))
+			}
+		}
+	}
+	if outputQuery != "" && len(teeOutputs) == 0 && format != "json" {
+		return "", fmt.Errorf("--query applies only to JSON output, not %q; pass --format json", format)
+	}
+	if outputQuery != "" && len(teeOutputs) != 0 && !hasJSONOutput() {
+		return "", errors.New("--query applies only to JSON output, but no --output destination is json")
+	}
+	for _, f := range allowed {
+		if format == f {
+			return format, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown format %q; expected one of %s", format, strings.Join(allowed, "|"))
+}
+
+func contains(list []string, s string) bool {
+	for _, t := range list {
+		if t == s {
+			return true
+		}
+	}
+	return false
+}
+
+// report prints the outcome of a change to the user's data, noting when it
+// was only pretend.
+func report(cmd *cobra.Command, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		msg = "(dry run) " + msg
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), msg)
+}
+
+// notify tells the user, on stderr, how a slow command is getting on, unless
+// they've asked for --quiet.
+func notify(cmd *cobra.Command, format string, args ...interface{}) {
+	if quiet, _ := cmd.Flags().GetBool("quiet"); !quiet {
+		fmt.Fprintf(cmd.ErrOrStderr(), format+"\n", args...)
+	}
+}
+
+// failFast returns true if batch commands are to stop at the first failure,
+// rather than carrying on & reporting the failures at the end.
+func failFast(cmd *cobra.Command) bool {
+	ff, _ := cmd.Flags().GetBool("fail-fast")
+	return ff
+}
+
+// terminalWidth returns the width of the terminal to which cmd's output is
+// going, or zero if it's not going to one.
+func terminalWidth(cmd *cobra.Command) int {
+
+	if outputFile != nil || !isTerminal(os.Stdout) {
+		return 0
+	}
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		log.Debug(fmt.Sprintf("Couldn't get the terminal size: %v", err))
+		return 0
+	}
+	return width
+}
+
+// outputFile is the file named by --output, if any.
+var outputFile *os.File
+
+// teeOutput is one of several destinations, each with its own format, given
+// as --output FORMAT:DEST.
+type teeOutput struct {
+	format string
+	name   string
+	w      io.Writer
+	// f is nil when writing to stdout
+	f *os.File
+	// noColor is true if tables written here are to be plain, whatever
+	// the terminal
+	noColor bool
+}
+
+// teeOutputs are the destinations given as --output FORMAT:DEST, if any.
+var teeOutputs []teeOutput
+
+// Commands that can write to several destinations at once carry this
+// annotation, listing the formats they support (comma-separated).
+const annotationTee = "teeFormats"
+
+// openOutput directs cmd's output to the file named by --output, if given.
+// If instead --output is given (perhaps repeatedly) as FORMAT:DEST, each
+// destination (a file, or "-" for stdout) is opened, to be written in its own
+// format.
+func openOutput(cmd *cobra.Command) error {
+
+	names, err := cmd.Flags().GetStringArray("output")
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	var plain []string
+	var tees []teeOutput
+	for _, name := range names {
+		i := strings.Index(name, ":")
+		if i < 0 || !isFormatName(name[:i]) {
+			plain = append(plain, name)
+			continue
+		}
+		tees = append(tees, teeOutput{format: name[:i], name: name[i+1:]})
+	}
+	switch {
+	case len(tees) == 0 && len(plain) == 1:
+		f, err := os.Create(plain[0])
+		if err != nil {
+			return err
+		}
+		outputFile = f
+		cmd.SetOut(f)
+		return nil
+	case len(tees) == 0:
+		return errors.New("--output may be repeated only in the form FORMAT:DEST (e.g. json:tags.json)")
+	case len(plain) != 0:
+		return fmt.Errorf("--output %q needs a FORMAT: prefix, as other destinations have one", plain[0])
+	}
+
+	formats, ok := cmd.Annotations[annotationTee]
+	if !ok {
+		return fmt.Errorf("pin %s can't write to several destinations; give --output just a file name", cmd.Name())
+	}
+	if cmd.Flags().Changed("format") {
+		return errors.New("--format can't be combined with --output FORMAT:DEST")
+	}
+	for _, t := range tees {
+		if !contains(strings.Split(formats, ","), t.format) {
+			return fmt.Errorf("unknown format %q in --output; expected one of %s", t.format, strings.ReplaceAll(formats, ",", "|"))
+		}
+		if t.name == "" {
+			return fmt.Errorf("--output %s: needs a destination (a file, or - for stdout)", t.format)
+		}
+	}
+	for i := range tees {
+		t := &tees[i]
+		switch t.name {
+		case "-":
+			t.w = cmd.OutOrStdout()
+		default:
+			t.f, err = os.Create(t.name)
+			if err != nil {
+				teeOutputs = tees[:i]
+				return err
+			}
+			t.w = t.f
+		}
+	}
+	teeOutputs = tees
+	return nil
+}
+
+// hasJSONOutput returns true if any of the destinations given as --output
+// FORMAT:DEST is to be written as JSON.
+func hasJSONOutput() bool {
+	for _, t := range teeOutputs {
+		if t.format == "json" {
+			return true
+		}
+	}
+	return false
+}
+
+// isFormatName returns true if s looks like the name of a format, so that
+// "s:..." is taken as FORMAT:DEST (rather than, say, a Windows path).
+func isFormatName(s string) bool {
+	if len(s) < 2 {
+		return false
+	}
+	for _, r := range s {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// closeOutput closes the file(s) named by --output, if any.
+func closeOutput() error {
+
+	var err error
+	if outputFile != nil {
+		err = outputFile.Close()
+	}
+	for _, t := range teeOutputs {
+		if t.f == nil {
+			continue
+		}
+		if cerr := t.f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// setColor decides, per --color, whether table output is to be colored. By
+// default ("auto"), it is only when stdout is a terminal, $NO_COLOR is unset,
+// and output isn't going to a file; each --output FORMAT:DEST is decided on
+// its own.
+func setColor(cmd *cobra.Command) error {
+
+	when, err := cmd.Flags().GetString("color")
+	if err != nil {
+		return err
+	}
+	switch when {
+	case "auto":
+		// color.NoColor is initialized according to stdout & $NO_COLOR
+		if outputFile != nil {
+			color.NoColor = true
+		}
+		for i := range teeOutputs {
+			teeOutputs[i].noColor = teeOutputs[i].f != nil
+		}
+	case "always":
+		color.NoColor = false
+	case "never":
+		color.NoColor = true
+	default:
+		return fmt.Errorf("invalid --color %q; expected one of auto|always|never", when)
+	}
+	return nil
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	if outputQuery != "" {
+		return writeQueried(w, v)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// getOutputTemplate parses cmd's --output-template, if given (returning nil
+// if not). The template is tried out on sample, a zero record, so that any
+// reference to a non-existent field is caught before any work is done.
+func getOutputTemplate(cmd *cobra.Command, sample interface{}) (*template.Template, error) {
+
+	text, err := cmd.Flags().GetString("output-template")
+	if err != nil {
+		return nil, err
+	}
+	if text == "" {
+		return nil, nil
+	}
+
+	tmpl, err := template.New("output").Funcs(template.FuncMap{"join": strings.Join}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --output-template: %v", err)
+	}
+	err = tmpl.Execute(io.Discard, sample)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --output-template: %v", err)
+	}
+	return tmpl, nil
+}
+
+// writeTemplate renders each of n records, as produced by item, through
+// tmpl, one per line.
+func writeTemplate(w io.Writer, tmpl *template.Template, n int, item func(i int) interface{}) error {
+	for i := 0; i < n; i++ {
+		if err := tmpl.Execute(w, item(i)); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeNDJSON writes n values, as produced by item, as JSON Lines: one JSON
+// document per line. Each is written as soon as it's encoded.
+func writeNDJSON(w io.Writer, n int, item func(i int) interface{}) error {
+	enc := json.NewEncoder(w)
+	for i := 0; i < n; i++ {
+		if err := enc.Encode(item(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// table is a simple text table, rendered in the same style as that produced
+// by get-tags.
+type table struct {
+	headers []string
+	// right[i] is true if column i should be right-aligned
+	right []bool
+	rows  [][]string
+	// color, if set, gives the color in which to show the cell at row i,
+	// column j (or nil for the default)
+	color func(i, j int) *color.Color
+	// fit, if non-zero, is the width of the terminal; the first column is
+	// truncated as needed for the table to fit within it
+	fit int
+	// noColor, if true, overrides color (& the bold headers)
+	noColor bool
+}
+
+// The first column isn't truncated below this width to fit a table on the
+// terminal; past that, the table just overflows.
+const minFitWidth = 8
+
+// displayWidth returns the number of terminal columns taken up by s: that's
+// one per rune, except that East Asian wide & full-width characters take two,
+// while combining marks (as in a decomposed "café") & invisible formatting
+// characters take none.
+func displayWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		n += runeWidth(r)
+	}
+	return n
+}
+
+func runeWidth(r rune) int {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case width.LookupRune(r).Kind() == width.EastAsianWide,
+		width.LookupRune(r).Kind() == width.EastAsianFullwidth:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// truncate shortens s, if need be, to at most max columns (when displayed),
+// marking the cut with an ellipsis; zero means no limit.
+func truncate(s string, max int) string {
+
+	if max <= 0 || displayWidth(s) <= max {
+		return s
+	}
+	n := 0
+	for i, r := range s {
+		n += runeWidth(r)
+		if n > max-1 {
+			return s[:i] + "…"
+		}
+	}
+	return s
+}
+
+// pad pads s with spaces to fill width columns.
+func pad(s string, width int, right bool) string {
+	n := width - displayWidth(s)
+	if n < 0 {
+		n = 0
+	}
+	fill := strings.Repeat(" ", n)
+	if right {
+		return fill + s
+	}
+	return s + fill
+}
+
+func (t *table) write(w io.Writer) error {
+
+	widths := make([]int, len(t.headers))
+	for i, h := range t.headers {
+		widths[i] = displayWidth(h)
+	}
+	for _, row := range t.rows {
+		for i, cell := range row {
+			if n := displayWidth(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	if t.fit > 0 && len(widths) != 0 {
+		total := 1
+		for _, width := range widths {
+			total += width + 3
+		}
+		if budget := widths[0] - (total - t.fit); total > t.fit && widths[0] > minFitWidth {
+			if budget < minFitWidth {
+				budget = minFitWidth
+			}
+			for _, row := range t.rows {
+				row[0] = truncate(row[0], budget)
+			}
+			widths[0] = budget
+		}
+	}
+
+	bold := color.New(color.Bold)
+	line := func(cells []string, cellColor func(j int) *color.Color) {
+		fmt.Fprint(w, "|")
+		for j, cell := range cells {
+			cell = pad(cell, widths[j], j < len(t.right) && t.right[j])
+			if c := cellColor(j); c != nil && !t.noColor {
+				cell = c.Sprint(cell)
+			}
+			fmt.Fprintf(w, " %s |", cell)
+		}
+		fmt.Fprintln(w)
+	}
+
+	rule := "+"
+	for _, width := range widths {
+		rule += strings.Repeat("-", width+2) + "+"
+	}
+
+	line(t.headers, func(int) *color.Color { return bold })
+	fmt.Fprintln(w, rule)
+	for i, row := range t.rows {
+		line(row, func(j int) *color.Color {
+			if t.color == nil {
+				return nil
+			}
+			return t.color(i, j)
+		})
+	}
+	_, err := fmt.Fprintln(w, rule)
+	return err
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+func TestOutputFile(t *testing.T) {
+
+	srv := newTestServer(t, tagsHandler(map[string]string{"go": "3", "emacs": "7"}, nil))
+	name := filepath.Join(t.TempDir(), "tags.txt")
+
+	stdout, _, err := runPin(t, srv, "--output", name, "get-tags", "--no-cache", "--format", "tsv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stdout != "" {
+		t.Errorf("wrote %q to stdout", stdout)
+	}
+	buf, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "go\t3\nemacs\t7\n"; string(buf) != want {
+		t.Errorf("got %q; want %q", buf, want)
+	}
+}
+
+// checkAligned fails unless every line of a table takes up the same number of
+// columns, returning that number.
+func checkAligned(t *testing.T, table string) int {
+
+	t.Helper()
+	lines := strings.Split(strings.TrimSuffix(table, "\n"), "\n")
+	width := displayWidth(lines[0])
+	for _, line := range lines[1:] {
+		if displayWidth(line) != width {
+			t.Errorf("misaligned table:\n%s", table)
+			break
+		}
+	}
+	return width
+}
+
+func TestTableWideCharacters(t *testing.T) {
+
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	var buf bytes.Buffer
+	tbl := table{
+		headers: []string{"Tag", "Use Count"},
+		right:   []bool{false, true},
+		rows:    [][]string{{"go", "3"}, {"日本語", "12"}, {"ｐｉｎ", "1"}},
+	}
+	err := tbl.write(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// "日本語" takes six columns
+	if got := checkAligned(t, buf.String()); got != 22 {
+		t.Errorf("table is %d columns wide; want 22:\n%s", got, buf.String())
+	}
+	if !strings.Contains(buf.String(), "| 日本語 |        12 |") {
+		t.Errorf("got:\n%s", buf.String())
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"go", 2},
+		{"café", 4},
+		{"cafe\u0301", 4},
+		{"naïve", 5},
+		{"日本語", 6},
+		{"ｐｉｎ", 6},
+		{"a\u200db", 2},
+	}
+	for _, test := range tests {
+		if got := displayWidth(test.s); got != test.want {
+			t.Errorf("displayWidth(%q) = %d; want %d", test.s, got, test.want)
+		}
+	}
+}
+
+// The right-hand border lines up whatever mix of accented & wide characters
+// the tags hold.
+func TestTableAccents(t *testing.T) {
+
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	var buf bytes.Buffer
+	err := writeTagsTable(&buf, &tagsOutput{tags: tagsOf("café", 1, "cafe\u0301", 2, "résumé", 3, "東京", 4, "go", 5)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := checkAligned(t, buf.String()); got != 22 {
+		t.Errorf("table is %d columns wide; want 22:\n%s", got, buf.String())
+	}
+}
+
+// truncate cuts on rune boundaries, never exceeding max columns, ellipsis &
+// all.
+func TestTruncate(t *testing.T) {
+
+	tests := []struct {
+		s    string
+		max  int
+		want string
+	}{
+		{"golang", 0, "golang"},
+		{"golang", 6, "golang"},
+		{"golang", 5, "gola…"},
+		{"golang", 1, "…"},
+		{"日本語タグ", 10, "日本語タグ"},
+		{"日本語タグ", 5, "日本…"},
+		// a wide rune that won't fit is dropped whole
+		{"日本語タグ", 4, "日…"},
+		{"日本語タグ", 2, "…"},
+		{"café-au-lait", 5, "café…"},
+		// combining marks stay with their base
+		{"cafe\u0301-au-lait", 5, "cafe\u0301…"},
+	}
+	for _, test := range tests {
+		got := truncate(test.s, test.max)
+		if got != test.want {
+			t.Errorf("truncate(%q, %d) = %q; want %q", test.s, test.max, got, test.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("truncate(%q, %d) split a rune", test.s, test.max)
+		}
+		if test.max > 0 && displayWidth(got) > test.max {
+			t.Errorf("truncate(%q, %d) is %d columns wide", test.s, test.max, displayWidth(got))
+		}
+	}
+}
+
+// --max-width applies to tables only.
+func TestGetTagsMaxWidth(t *testing.T) {
+
+	srv := newTestServer(t, tagsHandler(map[string]string{"日本語タグ": "2", "go": "1"}, nil))
+
+	stdout, _, err := runPin(t, srv, "get-tags", "--no-cache", "--max-width", "5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout, "日本…") || strings.Contains(stdout, "日本語") {
+		t.Errorf("the table wasn't truncated:\n%s", stdout)
+	}
+	checkAligned(t, stdout)
+
+	stdout, _, err = runPin(t, srv, "get-tags", "--no-cache", "--max-width", "5", "--format", "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout, "日本語タグ") {
+		t.Errorf("the CSV was truncated:\n%s", stdout)
+	}
+
+	_, _, err = runPin(t, srv, "get-tags", "--no-cache", "--max-width", "-1")
+	if err == nil {
+		t.Error("a negative --max-width was accepted")
+	}
+}
+
+// Each --output FORMAT:DEST gets its own rendering of the one response.
+func TestOutputTee(t *testing.T) {
+
+	calls := 0
+	srv := newTestServer(t, tagsHandler(map[string]string{"go": "3", "emacs": "7"}, &calls))
+	name := filepath.Join(t.TempDir(), "tags.json")
+
+	stdout, _, err := runPin(t, srv, "--output", "json:"+name, "--output", "plain:-", "get-tags", "--no-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Errorf("got %d requests for tags/get; want 1", calls)
+	}
+	if want := "go 3\nemacs 7\n"; stdout != want {
+		t.Errorf("got %q on stdout; want %q", stdout, want)
+	}
+	buf, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var listing tagListing
+	err = json.Unmarshal(buf, &listing)
+	if err != nil {
+		t.Fatalf("%s isn't JSON (%v): %q", name, err, buf)
+	}
+	if len(listing.Tags) != 2 || listing.TotalUses != 10 {
+		t.Errorf("got %+v", listing)
+	}
+
+	// --query applies to the json destinations alone
+	stdout, _, err = runPin(t, srv, "--output", "json:"+name, "--output", "plain:-", "--query", "total_uses", "get-tags", "--no-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "go 3\nemacs 7\n"; stdout != want {
+		t.Errorf("got %q on stdout; want %q", stdout, want)
+	}
+	buf, err = os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "10\n" {
+		t.Errorf("got %q in %s; want %q", buf, name, "10\n")
+	}
+}
+
+// Bad --output arguments are caught before anything's fetched.
+func TestOutputTeeErrors(t *testing.T) {
+
+	calls := 0
+	srv := newTestServer(t, tagsHandler(map[string]string{"go": "3"}, &calls))
+	dir := t.TempDir()
+
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"--output", "yaml:-", "get-tags"}, `unknown format "yaml"`},
+		{[]string{"--output", "json:", "get-tags"}, "needs a destination"},
+		{[]string{"--output", "json:-", "--output", filepath.Join(dir, "tags"), "get-tags"}, "needs a FORMAT: prefix"},
+		{[]string{"--output", filepath.Join(dir, "a"), "--output", filepath.Join(dir, "b"), "get-tags"}, "may be repeated only"},
+		{[]string{"--output", "json:-", "get-tags", "--format", "csv"}, "--format can't be combined"},
+		{[]string{"--output", "json:-", "last-update"}, "can't write to several destinations"},
+		{[]string{"--output", "table:-", "--output", "csv:" + filepath.Join(dir, "tags.csv"), "--query", "tags.#.name", "get-tags"}, "no --output destination is json"},
+		{[]string{"--output", "json:" + filepath.Join(dir, "missing", "tags.json"), "get-tags"}, "no such file"},
+	}
+	for _, test := range tests {
+		_, _, err := runPin(t, srv, test.args...)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%v: got %v; want %q", test.args, err, test.err)
+		}
+	}
+	if calls != 0 {
+		t.Errorf("got %d requests for tags/get", calls)
+	}
+}
+
+// With --color auto, a table written to a file is plain, while one written
+// to the terminal at the same time keeps its color.
+func TestOutputTeeColor(t *testing.T) {
+
+	// Pretend stdout is a terminal
+	saved := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = saved }()
+
+	srv := newTestServer(t, tagsHandler(map[string]string{"go": "3", "emacs": "7"}, nil))
+	name := filepath.Join(t.TempDir(), "tags.txt")
+
+	stdout, _, err := runPin(t, srv, "--output", "table:-", "--output", "table:"+name, "get-tags", "--no-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout, "\x1b[") {
+		t.Errorf("the table on stdout lost its color:\n%s", stdout)
+	}
+	buf, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(buf), "\x1b[") {
+		t.Errorf("the table in %s is colored:\n%s", name, buf)
+	}
+	if !strings.Contains(string(buf), "| emacs |         7 |") {
+		t.Errorf("got\n%s", buf)
+	}
+}
@@ -0,0 +1,894 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sp1ff/gopin/pinboard"
+	"github.com/spf13/cobra"
+)
+
+// Layout for bookmark timestamps in tables
+const postTimeLayout = "2006-01-02 15:04"
+
+// errEnoughPosts stops the retrieval of bookmarks once --count is reached.
+var errEnoughPosts = errors.New("enough bookmarks")
+
+func getBookmarks(cmd *cobra.Command, args []string) error {
+
+	tags, err := cmd.Flags().GetStringArray("tag")
+	if err != nil {
+		return err
+	}
+	count, err := cmd.Flags().GetInt("count")
+	if err != nil {
+		return err
+	}
+	format, err := getFormat(cmd, "table", "json", "ndjson", "csv")
+	if err != nil {
+		return err
+	}
+	spec, err := cmd.Flags().GetString("fields")
+	if err != nil {
+		return err
+	}
+	var fields []postField
+	if spec != "" {
+		fields, err = parsePostFields(spec)
+		if err != nil {
+			return err
+		}
+	}
+	matchAny, err := cmd.Flags().GetBool("any")
+	if err != nil {
+		return err
+	}
+	pageSize, err := cmd.Flags().GetInt("page-size")
+	if err != nil {
+		return err
+	}
+	page, err := cmd.Flags().GetInt("page")
+	if err != nil {
+		return err
+	}
+	if pageSize < 0 {
+		return errors.New("--page-size may not be negative")
+	}
+	if page < 1 {
+		return errors.New("pages are numbered from 1")
+	}
+	if cmd.Flags().Changed("page") && pageSize == 0 {
+		return errors.New("--page requires --page-size")
+	}
+	newer, older, err := getDateRange(cmd)
+	if err != nil {
+		return err
+	}
+	sinceSpec, err := cmd.Flags().GetString("since")
+	if err != nil {
+		return err
+	}
+	var since time.Time
+	if sinceSpec != "" {
+		since, err = parseDate(sinceSpec)
+		if err != nil {
+			return fmt.Errorf("--since: %v", err)
+		}
+	}
+	tmpl, err := getOutputTemplate(cmd, pinboard.Post{})
+	if err != nil {
+		return err
+	}
+	onlyPrivate, err := cmd.Flags().GetBool("only-private")
+	if err != nil {
+		return err
+	}
+	onlyPublic, err := cmd.Flags().GetBool("only-public")
+	if err != nil {
+		return err
+	}
+	toRead, err := cmd.Flags().GetBool("toread")
+	if err != nil {
+		return err
+	}
+
+	// Pinboard will AND together up to three tags; anything else has to be
+	// done here (in which case so does --count)
+	opts := pinboard.AllPostsOptions{Tags: tags, Results: count, From: since}
+	var rest []string
+	if matchAny {
+		opts.Tags, opts.Results = nil, 0
+	} else if len(tags) > pinboard.MaxFilterTags {
+		opts.Tags, rest = tags[:pinboard.MaxFilterTags], tags[pinboard.MaxFilterTags:]
+		opts.Results = 0
+	}
+	if !newer.IsZero() || !older.IsZero() || onlyPrivate || onlyPublic || toRead {
+		opts.Results = 0
+	}
+
+	keep := func(post pinboard.Post) bool {
+		if matchAny && len(tags) != 0 && !hasAnyTag(post, tags) {
+			return false
+		}
+		if len(rest) != 0 && !hasAllTags(post, rest) {
+			return false
+		}
+		if (onlyPrivate && post.Shared) || (onlyPublic && !post.Shared) {
+			return false
+		}
+		if toRead && !post.ToRead {
+			return false
+		}
+		return inDateRange(post, newer, older)
+	}
+
+	// Unless they're to be paged, CSV, ndjson & templated output may be
+	// written as the bookmarks arrive; otherwise, they're collected.
+	var posts []pinboard.Post
+	collect := func(post pinboard.Post) error {
+		posts = append(posts, post)
+		return nil
+	}
+	emit, finish := collect, func() error { return nil }
+	streaming := pageSize == 0 && (format == "csv" || format == "ndjson" || tmpl != nil)
+
+	client, err := newClient(cmd)
+	if err != nil {
+		return err
+	}
+	notify(cmd, "Retrieving bookmarks; Pinboard rate-limits this heavily, so it may be slow...")
+	p := startProgress(cmd, "Retrieving bookmarks", 0)
+	// Streamed bookmarks are written through the progress indicator, so
+	// that it's cleared first
+	if streaming {
+		emit, finish = newPostStream(cmd.OutOrStdout(), format, fields, tmpl)
+	}
+	n := 0
+	err = client.EachPost(cmd.Context(), opts, func(post pinboard.Post) error {
+		if !keep(post) {
+			return nil
+		}
+		if count > 0 && n >= count {
+			return errEnoughPosts
+		}
+		n += 1
+		p.set(n)
+		return emit(post)
+	})
+	p.stop()
+	if err != nil && !errors.Is(err, errEnoughPosts) {
+		return err
+	}
+	if streaming {
+		return finish()
+	}
+
+	var pg *pagination
+	if pageSize > 0 {
+		pg, err = paginate(len(posts), page, pageSize)
+		if err != nil {
+			return err
+		}
+		posts = posts[pg.first:pg.last]
+	}
+
+	if tmpl != nil {
+		format = "template"
+	}
+	switch format {
+	case "json":
+		var doc interface{} = posts
+		if fields != nil {
+			doc = postRecords(posts, fields)
+		}
+		if pg != nil {
+			doc = pagedPosts{pg, doc}
+		}
+		return writeJSON(cmd.OutOrStdout(), doc)
+	case "csv", "ndjson", "template":
+		emit, finish = newPostStream(cmd.OutOrStdout(), format, fields, tmpl)
+		for _, post := range posts {
+			err = emit(post)
+			if err != nil {
+				return err
+			}
+		}
+		return finish()
+	default:
+		if fields == nil {
+			fields, _ = parsePostFields(defaultTableFields)
+		}
+		err = writePostsTable(cmd.OutOrStdout(), posts, fields)
+		if err != nil {
+			return err
+		}
+		if pg != nil {
+			fmt.Fprintf(cmd.OutOrStdout(), "\nPage %d of %d\n", pg.Page, pg.Pages)
+		}
+		return nil
+	}
+}
+
+// pagination describes one page of a list of bookmarks.
+type pagination struct {
+	Page     int `json:"page"`
+	Pages    int `json:"pages"`
+	PageSize int `json:"page_size"`
+	Total    int `json:"total"`
+	// the page is [first, last) of the full list
+	first, last int
+}
+
+// paginate works out which of total items make up page, of size pageSize.
+func paginate(total, page, pageSize int) (*pagination, error) {
+
+	pages := (total + pageSize - 1) / pageSize
+	if pages == 0 {
+		pages = 1
+	}
+	if page > pages {
+		return nil, fmt.Errorf("there's no page %d; there are only %d", page, pages)
+	}
+
+	first := (page - 1) * pageSize
+	last := first + pageSize
+	if last > total {
+		last = total
+	}
+	return &pagination{Page: page, Pages: pages, PageSize: pageSize, Total: total, first: first, last: last}, nil
+}
+
+// pagedPosts is the JSON rendering of one page of bookmarks.
+type pagedPosts struct {
+	*pagination
+	Posts interface{} `json:"posts"`
+}
+
+// filterPosts returns those of posts for which keep returns true; it re-uses
+// the storage of posts.
+func filterPosts(posts []pinboard.Post, keep func(pinboard.Post) bool) []pinboard.Post {
+	result := posts[:0]
+	for _, post := range posts {
+		if keep(post) {
+			result = append(result, post)
+		}
+	}
+	return result
+}
+
+// getDateRange returns the times given by cmd's --newer-than & --older-than
+// flags; either may be zero, if the flag wasn't given.
+func getDateRange(cmd *cobra.Command) (newer, older time.Time, err error) {
+
+	for _, f := range []struct {
+		name string
+		t    *time.Time
+	}{{"newer-than", &newer}, {"older-than", &older}} {
+		s, err := cmd.Flags().GetString(f.name)
+		if err != nil {
+			return newer, older, err
+		}
+		if s == "" {
+			continue
+		}
+		*f.t, err = parseDate(s)
+		if err != nil {
+			return newer, older, fmt.Errorf("--%s: %v", f.name, err)
+		}
+	}
+	return newer, older, nil
+}
+
+// parseDate accepts an RFC 3339 timestamp, or a date (YYYY-MM-DD), taken to
+// mean the start of that day, local time.
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither a date (YYYY-MM-DD) nor an RFC 3339 timestamp", s)
+	}
+	return t, nil
+}
+
+// inDateRange reports whether post was made at or after newer, and before
+// older; either bound may be zero, meaning there is none.
+func inDateRange(post pinboard.Post, newer, older time.Time) bool {
+	if !newer.IsZero() && post.Time.Before(newer) {
+		return false
+	}
+	if !older.IsZero() && !post.Time.Before(older) {
+		return false
+	}
+	return true
+}
+
+// hasTag reports whether post bears tag; like Pinboard, ignore case.
+func hasTag(post pinboard.Post, tag string) bool {
+	for _, t := range post.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAllTags(post pinboard.Post, tags []string) bool {
+	for _, tag := range tags {
+		if !hasTag(post, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasAnyTag(post pinboard.Post, tags []string) bool {
+	for _, tag := range tags {
+		if hasTag(post, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Pinboard permits posts/recent only once a minute, so hang on to its result
+// for that long.
+const recentCacheTTL = time.Minute
+
+func recent(cmd *cobra.Command, args []string) error {
+
+	tags, err := cmd.Flags().GetStringArray("tag")
+	if err != nil {
+		return err
+	}
+	count, err := cmd.Flags().GetInt("count")
+	if err != nil {
+		return err
+	}
+	format, err := getFormat(cmd, "table", "json")
+	if err != nil {
+		return err
+	}
+	if len(tags) > pinboard.MaxFilterTags {
+		return fmt.Errorf("at most %d tags may be given", pinboard.MaxFilterTags)
+	}
+	if count < 1 || count > pinboard.MaxRecentPosts {
+		return fmt.Errorf("--count must be between 1 and %d", pinboard.MaxRecentPosts)
+	}
+	newer, older, err := getDateRange(cmd)
+	if err != nil {
+		return err
+	}
+
+	token := cmd.Flag("token").Value.String()
+	key := cacheKey("recent", token, strconv.Itoa(count), strings.Join(tags, " "))
+	var posts []pinboard.Post
+	if !readCache(key, recentCacheTTL, &posts) {
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+		posts, err = client.GetRecentPosts(cmd.Context(), tags, count)
+		if err != nil {
+			return err
+		}
+		writeCache(key, posts)
+	}
+	posts = filterPosts(posts, func(post pinboard.Post) bool { return inDateRange(post, newer, older) })
+
+	switch format {
+	case "json":
+		return writeJSON(cmd.OutOrStdout(), posts)
+	default:
+		fields, _ := parsePostFields(defaultTableFields)
+		return writePostsTable(cmd.OutOrStdout(), posts, fields)
+	}
+}
+
+func addBookmark(cmd *cobra.Command, args []string) error {
+
+	var post pinboard.Post
+	var err error
+	post.URL, err = cmd.Flags().GetString("url")
+	if err != nil {
+		return err
+	}
+	post.Description, err = cmd.Flags().GetString("title")
+	if err != nil {
+		return err
+	}
+	post.Extended, err = cmd.Flags().GetString("extended")
+	if err != nil {
+		return err
+	}
+	post.Tags, err = cmd.Flags().GetStringArray("tag")
+	if err != nil {
+		return err
+	}
+	private, err := cmd.Flags().GetBool("private")
+	if err != nil {
+		return err
+	}
+	post.Shared = !private
+	post.ToRead, err = cmd.Flags().GetBool("toread")
+	if err != nil {
+		return err
+	}
+	replace, err := cmd.Flags().GetBool("replace")
+	if err != nil {
+		return err
+	}
+	fromFile, err := cmd.Flags().GetString("from-file")
+	if err != nil {
+		return err
+	}
+	err = validateTags(post.Tags)
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient(cmd)
+	if err != nil {
+		return err
+	}
+	if fromFile != "" {
+		return addBookmarksFromFile(cmd, client, fromFile, post, replace)
+	}
+	return addPost(cmd, client, post, replace)
+}
+
+// addPost bookmarks post, reporting it.
+func addPost(cmd *cobra.Command, client *pinboard.Client, post pinboard.Post, replace bool) error {
+
+	err := client.AddPost(cmd.Context(), post, replace)
+	if err != nil {
+		return fmt.Errorf("failed to add %q: %w", post.URL, err)
+	}
+
+	report(cmd, "Added %q.", post.URL)
+	return nil
+}
+
+func deleteBookmark(cmd *cobra.Command, args []string) error {
+
+	u, err := cmd.Flags().GetString("url")
+	if err != nil {
+		return err
+	}
+	yes, err := cmd.Flags().GetBool("yes")
+	if err != nil {
+		return err
+	}
+
+	if !yes {
+		ok, err := confirm(cmd, fmt.Sprintf("Delete the bookmark for %q?", u))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintln(cmd.ErrOrStderr(), "Not deleted.")
+			return nil
+		}
+	}
+
+	client, err := newClient(cmd)
+	if err != nil {
+		return err
+	}
+	err = client.DeletePost(cmd.Context(), u)
+	if errors.Is(err, pinboard.ErrNotFound) {
+		return fmt.Errorf("no bookmark found for %q", u)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete %q: %w", u, err)
+	}
+
+	report(cmd, "Deleted %q.", u)
+	return nil
+}
+
+// markRead clears the "to read" flag on the bookmark for --url. Pinboard has
+// no call for just that, so the bookmark is fetched & re-added, as is but for
+// the flag.
+func markRead(cmd *cobra.Command, args []string) error {
+
+	u, err := cmd.Flags().GetString("url")
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient(cmd)
+	if err != nil {
+		return err
+	}
+	post, err := client.GetPost(cmd.Context(), u)
+	if errors.Is(err, pinboard.ErrNotFound) {
+		return fmt.Errorf("no bookmark found for %q", u)
+	}
+	if err != nil {
+		return err
+	}
+	if !post.ToRead {
+		fmt.Fprintf(cmd.OutOrStdout(), "%q is already marked as read.\n", u)
+		return nil
+	}
+
+	post.ToRead = false
+	err = client.AddPost(cmd.Context(), post, true)
+	if err != nil {
+		return fmt.Errorf("failed to mark %q as read: %w", u, err)
+	}
+
+	report(cmd, "Marked %q as read.", u)
+	return nil
+}
+
+func suggestTags(cmd *cobra.Command, args []string) error {
+
+	u, err := cmd.Flags().GetString("url")
+	if err != nil {
+		return err
+	}
+	merge, err := cmd.Flags().GetBool("merge")
+	if err != nil {
+		return err
+	}
+	format, err := getFormat(cmd, "text", "json")
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient(cmd)
+	if err != nil {
+		return err
+	}
+	suggestions, err := client.SuggestTags(cmd.Context(), u)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if merge {
+		merged := []string{}
+		seen := make(map[string]bool)
+		for _, tag := range append(suggestions.Popular, suggestions.Recommended...) {
+			if !seen[tag] {
+				seen[tag] = true
+				merged = append(merged, tag)
+			}
+		}
+		if format == "json" {
+			return writeJSON(out, merged)
+		}
+		for _, tag := range merged {
+			fmt.Fprintln(out, tag)
+		}
+		return nil
+	}
+
+	if format == "json" {
+		return writeJSON(out, suggestions)
+	}
+	fmt.Fprintln(out, "Popular:")
+	for _, tag := range suggestions.Popular {
+		fmt.Fprintf(out, "    %s\n", tag)
+	}
+	fmt.Fprintln(out, "Recommended:")
+	for _, tag := range suggestions.Recommended {
+		fmt.Fprintf(out, "    %s\n", tag)
+	}
+	return nil
+}
+
+func dates(cmd *cobra.Command, args []string) error {
+
+	tag, err := cmd.Flags().GetString("tag")
+	if err != nil {
+		return err
+	}
+	format, err := getFormat(cmd, "table", "json")
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient(cmd)
+	if err != nil {
+		return err
+	}
+	counts, err := client.GetPostDates(cmd.Context(), tag)
+	if err != nil {
+		return err
+	}
+
+	total := uint64(0)
+	for _, dc := range counts {
+		total += dc.Count
+	}
+
+	out := cmd.OutOrStdout()
+	if format == "json" {
+		return writeJSON(out, struct {
+			Dates []pinboard.DateCount `json:"dates"`
+			Total uint64               `json:"total"`
+		}{counts, total})
+	}
+
+	t := table{headers: []string{"Date", "Bookmarks"}, right: []bool{false, true}}
+	for _, dc := range counts {
+		t.rows = append(t.rows, []string{dc.Date, strconv.FormatUint(dc.Count, 10)})
+	}
+	err = t.write(out)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Total: %d bookmarks over %d days\n", total, len(counts))
+	return nil
+}
+
+func lastUpdate(cmd *cobra.Command, args []string) error {
+
+	utc, err := cmd.Flags().GetBool("utc")
+	if err != nil {
+		return err
+	}
+	wait, err := cmd.Flags().GetBool("wait")
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient(cmd)
+	if err != nil {
+		return err
+	}
+	var t time.Time
+	if wait {
+		t, err = waitForUpdate(cmd, client)
+	} else {
+		t, err = client.LastUpdate(cmd.Context())
+	}
+	if err != nil {
+		return err
+	}
+
+	if utc {
+		t = t.UTC()
+	} else {
+		t = t.Local()
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), t.Format(time.RFC3339))
+	return nil
+}
+
+// waitForUpdate polls posts/update every --interval until the time of the
+// last change passes the baseline (--after, or else the time when first
+// polled), returning the new time.
+func waitForUpdate(cmd *cobra.Command, client *pinboard.Client) (time.Time, error) {
+
+	interval, err := cmd.Flags().GetDuration("interval")
+	if err != nil {
+		return time.Time{}, err
+	}
+	if interval <= 0 {
+		return time.Time{}, fmt.Errorf("--interval must be positive, not %v", interval)
+	}
+	limit, err := cmd.Flags().GetDuration("wait-timeout")
+	if err != nil {
+		return time.Time{}, err
+	}
+	after, err := cmd.Flags().GetString("after")
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	ctx := cmd.Context()
+	if limit > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limit)
+		defer cancel()
+	}
+
+	var baseline time.Time
+	if after != "" {
+		baseline, err = parseDate(after)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid --after: %v", err)
+		}
+	} else {
+		baseline, err = client.LastUpdate(ctx)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if quiet, _ := cmd.Flags().GetBool("quiet"); !quiet {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Last changed %s; waiting for a change...\n",
+				baseline.Local().Format(time.RFC3339))
+		}
+		if !sleepCtx(ctx, interval) {
+			return time.Time{}, waitDone(cmd.Context(), limit)
+		}
+	}
+
+	for {
+		t, err := client.LastUpdate(ctx)
+		if err != nil {
+			if ctx.Err() != nil && cmd.Context().Err() == nil {
+				return time.Time{}, waitDone(cmd.Context(), limit)
+			}
+			return time.Time{}, err
+		}
+		if t.After(baseline) {
+			return t, nil
+		}
+		log.Debug(fmt.Sprintf("No change since %s; polling again in %v.", baseline.Format(time.RFC3339), interval))
+		if !sleepCtx(ctx, interval) {
+			return time.Time{}, waitDone(cmd.Context(), limit)
+		}
+	}
+}
+
+// sleepCtx sleeps for d, returning false if ctx is done first.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// waitDone explains why waitForUpdate stopped waiting: either the command
+// was cancelled (ctx is done), or the --wait-timeout of limit passed.
+func waitDone(ctx context.Context, limit time.Duration) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return fmt.Errorf("no change to your bookmarks within %v", limit)
+}
+
+var getBookmarksCmd = &cobra.Command{
+	Use:   "get-bookmarks",
+	Short: "Retrieve all your bookmarks",
+	Long: `Retrieve all your bookmarks, optionally only those with given tags.
+
+By default, only bookmarks bearing all the tags given with --tag are shown.
+Pinboard itself will filter on at most three tags; any more are applied
+locally. With --any, bookmarks bearing any of the tags are shown; as Pinboard
+can't do that, all your bookmarks are retrieved & filtered locally. Likewise
+--newer-than & --older-than, while --since is passed to Pinboard, so that
+only the new bookmarks are downloaded (handy for incremental syncs).
+
+Pinboard returns all the matching bookmarks at once; to view them a page at a
+time, give --page-size (and --page). In JSON, a page of bookmarks is wrapped
+in an object noting the page number, the number of pages & the total.`,
+	Args: cobra.NoArgs,
+	RunE: getBookmarks,
+}
+
+var recentCmd = &cobra.Command{
+	Use:   "recent",
+	Short: "Retrieve your most recent bookmarks",
+	Args:  cobra.NoArgs,
+	RunE:  recent,
+}
+
+var addBookmarkCmd = &cobra.Command{
+	Use:   "add-bookmark",
+	Short: "Bookmark a URL",
+	Long: `Bookmark a URL, or each of those listed in a CSV file.
+
+With --from-file, the file's first line names its columns, from url, title,
+tags (space-separated) & extended; url & title are required. --tag, --private
+& --toread apply to every bookmark in the file. Bad rows are reported, by
+line number, but don't stop the rest (unless given --fail-fast).`,
+	Args: cobra.NoArgs,
+	RunE: addBookmark,
+}
+
+var deleteBookmarkCmd = &cobra.Command{
+	Use:   "delete-bookmark",
+	Short: "Delete the bookmark for a URL",
+	Args:  cobra.NoArgs,
+	RunE:  deleteBookmark,
+}
+
+var markReadCmd = &cobra.Command{
+	Use:   "mark-read",
+	Short: "Mark a bookmark as read",
+	Args:  cobra.NoArgs,
+	RunE:  markRead,
+}
+
+var suggestTagsCmd = &cobra.Command{
+	Use:   "suggest-tags",
+	Short: "Suggest tags for a URL",
+	Args:  cobra.NoArgs,
+	RunE:  suggestTags,
+}
+
+var datesCmd = &cobra.Command{
+	Use:   "dates",
+	Short: "Count the bookmarks you created on each day",
+	Args:  cobra.NoArgs,
+	RunE:  dates,
+}
+
+var lastUpdateCmd = &cobra.Command{
+	Use:   "last-update",
+	Short: "Show when your bookmarks last changed",
+	Long: `Show when your bookmarks last changed.
+
+With --wait, poll Pinboard every --interval until they change again (or
+change after --after), then show the new time: handy for re-syncing only
+when there's something new. --wait-timeout limits the wait.`,
+	Args: cobra.NoArgs,
+	RunE: lastUpdate,
+}
+
+func init() {
+	getBookmarksCmd.Flags().StringArray("tag", nil, "Only retrieve bookmarks with this tag (may be repeated)")
+	getBookmarksCmd.Flags().Bool("any", false, "Retrieve bookmarks with any, rather than all, of the given tags")
+	getBookmarksCmd.Flags().Int("count", 0, "Retrieve at most this many bookmarks (zero for all)")
+	getBookmarksCmd.Flags().StringP("format", "f", "table", "Output format: table|json|ndjson|csv")
+	getBookmarksCmd.Flags().String("fields", "", "Comma-separated fields to show, in order (e.g. url,description,tags)")
+	getBookmarksCmd.Flags().String("output-template", "", "Write each bookmark per this Go template (e.g. '{{.URL}} {{join .Tags \",\"}}')")
+	getBookmarksCmd.MarkFlagsMutuallyExclusive("format", "output-template")
+	getBookmarksCmd.MarkFlagsMutuallyExclusive("fields", "output-template")
+	getBookmarksCmd.Flags().String("newer-than", "", "Only show bookmarks made at or after this date (YYYY-MM-DD, or RFC 3339)")
+	getBookmarksCmd.Flags().String("older-than", "", "Only show bookmarks made before this date (YYYY-MM-DD, or RFC 3339)")
+	getBookmarksCmd.Flags().Bool("only-private", false, "Only show private bookmarks")
+	getBookmarksCmd.Flags().Bool("only-public", false, "Only show public (shared) bookmarks")
+	getBookmarksCmd.MarkFlagsMutuallyExclusive("only-private", "only-public")
+	getBookmarksCmd.Flags().Bool("toread", false, "Only show bookmarks marked as unread")
+	getBookmarksCmd.Flags().String("since", "", "Only retrieve bookmarks made after this time, filtered by Pinboard (YYYY-MM-DD, or RFC 3339)")
+	getBookmarksCmd.Flags().Int("page-size", 0, "Show this many bookmarks per page (zero for no paging)")
+	getBookmarksCmd.Flags().Int("page", 1, "Show this page of bookmarks (with --page-size)")
+
+	recentCmd.Flags().StringArray("tag", nil, "Only retrieve bookmarks with this tag (may be given up to three times)")
+	recentCmd.Flags().Int("count", 15, "Retrieve this many bookmarks (at most 100)")
+	recentCmd.Flags().StringP("format", "f", "table", "Output format: table|json")
+	recentCmd.Flags().String("newer-than", "", "Only show bookmarks made at or after this date (YYYY-MM-DD, or RFC 3339)")
+	recentCmd.Flags().String("older-than", "", "Only show bookmarks made before this date (YYYY-MM-DD, or RFC 3339)")
+
+	addBookmarkCmd.Flags().String("url", "", "URL to bookmark (required, unless given --from-file)")
+	addBookmarkCmd.Flags().String("title", "", "Title of the bookmark (required with --url)")
+	addBookmarkCmd.Flags().String("extended", "", "Longer description of the bookmark")
+	addBookmarkCmd.Flags().StringArray("tag", nil, "Tag the bookmark (may be repeated)")
+	addBookmarkCmd.Flags().Bool("private", false, "Make the bookmark private")
+	addBookmarkCmd.Flags().Bool("toread", false, "Mark the bookmark as unread")
+	addBookmarkCmd.Flags().Bool("replace", true, "Replace any existing bookmark for this URL")
+	addBookmarkCmd.Flags().String("from-file", "", "Bookmark each URL listed in this CSV file (- for stdin)")
+	addBookmarkCmd.MarkFlagsOneRequired("url", "from-file")
+	addBookmarkCmd.MarkFlagsRequiredTogether("url", "title")
+	addBookmarkCmd.MarkFlagsMutuallyExclusive("from-file", "url")
+	addBookmarkCmd.MarkFlagsMutuallyExclusive("from-file", "title")
+	addBookmarkCmd.MarkFlagsMutuallyExclusive("from-file", "extended")
+
+	markReadCmd.Flags().String("url", "", "URL whose bookmark is to be marked as read (required)")
+	markReadCmd.MarkFlagRequired("url")
+
+	deleteBookmarkCmd.Flags().String("url", "", "URL whose bookmark is to be deleted (required)")
+	deleteBookmarkCmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation")
+	deleteBookmarkCmd.MarkFlagRequired("url")
+
+	suggestTagsCmd.Flags().String("url", "", "URL for which tags are wanted (required)")
+	suggestTagsCmd.Flags().Bool("merge", false, "Merge popular & recommended tags into one list")
+	suggestTagsCmd.Flags().StringP("format", "f", "text", "Output format: text|json")
+	suggestTagsCmd.MarkFlagRequired("url")
+
+	datesCmd.Flags().String("tag", "", "Only count bookmarks with this tag")
+	datesCmd.Flags().StringP("format", "f", "table", "Output format: table|json")
+
+	lastUpdateCmd.Flags().Bool("utc", false, "Show the time in UTC rather than the local zone")
+	lastUpdateCmd.Flags().Bool("wait", false, "Wait until your bookmarks change")
+	lastUpdateCmd.Flags().Duration("interval", time.Minute, "With --wait, how often to ask Pinboard")
+	lastUpdateCmd.Flags().Duration("wait-timeout", 0, "With --wait, give up after this long (zero for never)")
+	lastUpdateCmd.Flags().String("after", "", "With --wait, wait for a change after this time (RFC3339 or YYYY-MM-DD; default now)")
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sp1ff/gopin/pinboard"
+)
+
+// --newer-than is inclusive, --older-than exclusive.
+func TestInDateRange(t *testing.T) {
+
+	day := func(d int) time.Time { return time.Date(2024, time.March, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		posted, newer, older time.Time
+		want                 bool
+	}{
+		{day(10), time.Time{}, time.Time{}, true},
+		{day(10), day(10), time.Time{}, true},
+		{day(10).Add(-time.Second), day(10), time.Time{}, false},
+		{day(10), time.Time{}, day(10), false},
+		{day(10).Add(-time.Second), time.Time{}, day(10), true},
+		{day(10), day(10), day(11), true},
+		{day(11), day(10), day(11), false},
+		{day(9), day(10), day(11), false},
+	}
+	for _, test := range tests {
+		got := inDateRange(pinboard.Post{Time: test.posted}, test.newer, test.older)
+		if got != test.want {
+			t.Errorf("inDateRange(%v, %v, %v) = %v", test.posted, test.newer, test.older, got)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+
+	got, err := parseDate("2024-03-10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, time.March, 10, 0, 0, 0, 0, time.Local); !got.Equal(want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+	got, err = parseDate("2024-03-10T12:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+	for _, s := range []string{"", "10/03/2024", "2024-13-01"} {
+		if _, err := parseDate(s); err == nil {
+			t.Errorf("parseDate(%q) succeeded", s)
+		}
+	}
+}
+
+// testPosts are the bookmarks with which tests seed fakePinboard: one of
+// each combination of shared & toread.
+func testPosts() []pinboard.Post {
+	at := time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC)
+	return []pinboard.Post{
+		{URL: "https://public.example/", Description: "public", Tags: []string{"go"}, Time: at, Shared: true},
+		{URL: "https://private.example/", Description: "private", Tags: []string{"go"}, Time: at},
+		{URL: "https://public-unread.example/", Description: "public, unread", Time: at, Shared: true, ToRead: true},
+		{URL: "https://private-unread.example/", Description: "private, unread", Time: at, ToRead: true},
+	}
+}
+
+// getBookmarkURLs runs get-bookmarks against srv, returning the URLs of the
+// bookmarks it lists.
+func getBookmarkURLs(t *testing.T, srv *httptest.Server, args ...string) []string {
+
+	t.Helper()
+	stdout, _, err := runPin(t, srv, append([]string{"get-bookmarks", "--format", "json"}, args...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var posts []pinboard.Post
+	err = json.Unmarshal([]byte(stdout), &posts)
+	if err != nil {
+		t.Fatalf("bad output %q: %v", stdout, err)
+	}
+	var urls []string
+	for _, post := range posts {
+		urls = append(urls, post.URL)
+	}
+	return urls
+}
+
+func TestGetBookmarksVisibility(t *testing.T) {
+
+	f := &fakePinboard{posts: testPosts()}
+	srv := newTestServer(t, f.ServeHTTP)
+
+	tests := []struct {
+		flag string
+		want []string
+	}{
+		{"--only-private", []string{"https://private.example/", "https://private-unread.example/"}},
+		{"--only-public", []string{"https://public.example/", "https://public-unread.example/"}},
+	}
+	for _, test := range tests {
+		if got := getBookmarkURLs(t, srv, test.flag); !equalStrings(got, test.want) {
+			t.Errorf("%s: got %v; want %v", test.flag, got, test.want)
+		}
+	}
+
+	_, _, err := runPin(t, srv, "get-bookmarks", "--only-private", "--only-public")
+	if err == nil {
+		t.Error("--only-private & --only-public were accepted together")
+	}
+
+	stdout, _, err := runPin(t, srv, "get-bookmarks", "--format", "csv", "--fields", "url,visibility", "--only-private")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "url,visibility\nhttps://private.example/,private\nhttps://private-unread.example/,private\n"
+	if stdout != want {
+		t.Errorf("got %q; want %q", stdout, want)
+	}
+}
+
+func TestGetBookmarksToRead(t *testing.T) {
+
+	f := &fakePinboard{posts: testPosts()}
+	srv := newTestServer(t, f.ServeHTTP)
+
+	want := []string{"https://public-unread.example/", "https://private-unread.example/"}
+	if got := getBookmarkURLs(t, srv, "--toread"); !equalStrings(got, want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+	// --toread composes with the other filters
+	want = []string{"https://private-unread.example/"}
+	if got := getBookmarkURLs(t, srv, "--toread", "--only-private"); !equalStrings(got, want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+}
+
+// mark-read fetches the bookmark, then re-adds it as it was but for the flag.
+func TestMarkRead(t *testing.T) {
+
+	f := &fakePinboard{posts: testPosts()}
+	f.posts[2].Extended, f.posts[2].Tags = "notes", []string{"go", "toread"}
+	srv := newTestServer(t, f.ServeHTTP)
+
+	u := f.posts[2].URL
+	stdout, _, err := runPin(t, srv, "mark-read", "--url", u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdout, "Marked") {
+		t.Errorf("got %q", stdout)
+	}
+	if got := f.calls("posts/get"); len(got) != 1 || got[0].Get("url") != u {
+		t.Errorf("got posts/get requests %v", got)
+	}
+	adds := f.calls("posts/add")
+	if len(adds) != 1 {
+		t.Fatalf("got posts/add requests %v", adds)
+	}
+	q := adds[0]
+	want := map[string]string{
+		"url":         u,
+		"description": "public, unread",
+		"extended":    "notes",
+		"tags":        "go toread",
+		"dt":          "2024-01-02T03:04:05Z",
+		"shared":      "yes",
+		"toread":      "no",
+		"replace":     "yes",
+	}
+	for k, v := range want {
+		if q.Get(k) != v {
+			t.Errorf("%s: got %q; want %q", k, q.Get(k), v)
+		}
+	}
+
+	// Bookmarks already read are left alone...
+	_, _, err = runPin(t, srv, "mark-read", "--url", u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(f.calls("posts/add")); n != 1 {
+		t.Errorf("the bookmark was re-added (%d adds)", n)
+	}
+
+	// ...& unknown URLs are reported.
+	_, _, err = runPin(t, srv, "mark-read", "--url", "https://nowhere.example/")
+	if err == nil || !strings.Contains(err.Error(), `no bookmark found for "https://nowhere.example/"`) {
+		t.Errorf("got %v", err)
+	}
+}
+
+// --since is passed to Pinboard, rather than applied here.
+func TestGetBookmarksSince(t *testing.T) {
+
+	f := &fakePinboard{posts: testPosts()}
+	srv := newTestServer(t, f.ServeHTTP)
+
+	tests := []struct {
+		since, want string
+	}{
+		{"2024-03-10T12:00:00+02:00", "2024-03-10T10:00:00Z"},
+		{"2024-03-10", time.Date(2024, time.March, 10, 0, 0, 0, 0, time.Local).UTC().Format(time.RFC3339)},
+	}
+	for i, test := range tests {
+		getBookmarkURLs(t, srv, "--since", test.since)
+		calls := f.calls("posts/all")
+		if len(calls) != i+1 {
+			t.Fatalf("got %d requests for posts/all", len(calls))
+		}
+		if got := calls[i].Get("fromdt"); got != test.want {
+			t.Errorf("--since %s: got fromdt %q; want %q", test.since, got, test.want)
+		}
+	}
+
+	getBookmarkURLs(t, srv)
+	if q := f.calls("posts/all")[len(tests)]; q.Has("fromdt") {
+		t.Errorf("fromdt sent without --since: %v", q)
+	}
+	_, _, err := runPin(t, srv, "get-bookmarks", "--since", "last tuesday")
+	if err == nil || !strings.HasPrefix(err.Error(), "--since:") {
+		t.Errorf("got %v", err)
+	}
+}
+
+// screen renders out as a terminal would show it, interpreting carriage
+// returns & "erase to end of line".
+func screen(out string) []string {
+
+	var lines []string
+	var line []rune
+	col := 0
+	for i := 0; i < len(out); {
+		r, n := utf8.DecodeRuneInString(out[i:])
+		switch {
+		case strings.HasPrefix(out[i:], "\x1b[K"):
+			line, n = line[:col], 3
+		case r == '\r':
+			col = 0
+		case r == '\n':
+			lines, line, col = append(lines, string(line)), nil, 0
+		case col < len(line):
+			line[col] = r
+			col += 1
+		default:
+			line = append(line, r)
+			col += 1
+		}
+		i += n
+	}
+	if len(line) != 0 {
+		lines = append(lines, string(line))
+	}
+	return lines
+}
+
+// On a terminal, bookmarks streamed while the progress indicator is shown
+// each start on a line of their own.
+func TestGetBookmarksStreamProgress(t *testing.T) {
+
+	saved := isTerminal
+	isTerminal = func(f *os.File) bool { return f == os.Stderr }
+	defer func() { isTerminal = saved }()
+	t.Setenv("NO_COLOR", "")
+
+	f := &fakePinboard{posts: testPosts()}
+	srv := newTestServer(t, f.ServeHTTP)
+
+	// stdout & stderr are one & the same on a terminal
+	var term bytes.Buffer
+	err := runPinTo(t, srv, &term, &term, "get-bookmarks", "--format", "csv", "--fields", "url")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(term.String(), "Retrieving bookmarks") {
+		t.Fatalf("the progress indicator wasn't shown: %q", term.String())
+	}
+	want := []string{
+		"Retrieving bookmarks; Pinboard rate-limits this heavily, so it may be slow...",
+		"url",
+		"https://public.example/",
+		"https://private.example/",
+		"https://public-unread.example/",
+		"https://private-unread.example/",
+	}
+	if got := screen(term.String()); !equalStrings(got, want) {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// progress is a spinner, optionally with an n/total counter, shown on stderr
+// while a slow command runs. It's only shown on a terminal, and not at all
+// given --quiet or $NO_COLOR. Anything else written to the command's output,
+// or logged, while it's shown clears it; once counting, it reappears only on
+// the next call to set (so as not to overwrite a prompt, say).
+type progress struct {
+	enabled bool
+	cmd     *cobra.Command
+	out     io.Writer
+	err     io.Writer
+	logOut  io.Writer
+
+	mu      sync.Mutex
+	label   string
+	n       int
+	total   int
+	frame   int
+	visible bool // whether the indicator should be shown
+	counted bool // whether set has been called
+	drawn   bool // whether it's on the screen
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+var spinnerFrames = []byte(`|/-\`)
+
+// startProgress shows label with a spinner; if total is non-zero, set is
+// expected to be called with the count of items processed so far.
+func startProgress(cmd *cobra.Command, label string, total int) *progress {
+
+	quiet, _ := cmd.Flags().GetBool("quiet")
+	if quiet || !isTerminal(os.Stderr) || os.Getenv("NO_COLOR") != "" {
+		return &progress{}
+	}
+
+	p := &progress{
+		enabled: true,
+		cmd:     cmd,
+		out:     cmd.OutOrStdout(),
+		err:     cmd.ErrOrStderr(),
+		logOut:  log.StandardLogger().Out,
+		label:   label,
+		total:   total,
+		visible: true,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	cmd.SetOut(&progressWriter{p, p.out})
+	cmd.SetErr(&progressWriter{p, p.err})
+	log.SetOutput(&progressWriter{p, p.logOut})
+
+	go p.run()
+	return p
+}
+
+func (p *progress) run() {
+	defer close(p.stopped)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			p.frame += 1
+			if p.visible {
+				p.draw()
+			}
+			p.mu.Unlock()
+		}
+	}
+}
+
+// draw & clear are called with mu held.
+func (p *progress) draw() {
+	fmt.Fprintf(p.err, "\r%c %s", spinnerFrames[p.frame%len(spinnerFrames)], p.label)
+	if p.total > 0 {
+		fmt.Fprintf(p.err, " %d/%d", p.n, p.total)
+	} else if p.n > 0 {
+		fmt.Fprintf(p.err, " %d", p.n)
+	}
+	fmt.Fprint(p.err, "\x1b[K")
+	p.drawn = true
+}
+
+func (p *progress) clear() {
+	if p.drawn {
+		fmt.Fprint(p.err, "\r\x1b[K")
+		p.drawn = false
+	}
+}
+
+// set updates the count of items processed.
+func (p *progress) set(n int) {
+	if !p.enabled {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.n = n
+	p.counted = true
+	p.visible = true
+	p.draw()
+}
+
+// stop removes the indicator, restoring the command's output; it may be
+// called more than once.
+func (p *progress) stop() {
+	if !p.enabled {
+		return
+	}
+	p.enabled = false
+	close(p.done)
+	<-p.stopped
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+	p.visible = false
+	p.cmd.SetOut(p.out)
+	p.cmd.SetErr(p.err)
+	log.SetOutput(p.logOut)
+}
+
+// progressWriter clears the progress indicator before writing to w.
+type progressWriter struct {
+	p *progress
+	w io.Writer
+}
+
+func (pw *progressWriter) Write(b []byte) (int, error) {
+	pw.p.mu.Lock()
+	defer pw.p.mu.Unlock()
+	pw.p.clear()
+	if pw.p.counted {
+		pw.p.visible = false
+	}
+	return pw.w.Write(b)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// isTerminal returns true if f is a terminal; it's a variable so that tests
+// can pretend.
+var isTerminal = func(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// confirm puts question to the user, returning true only on an explicit "y"
+// or "yes". When stdin isn't a terminal there's no-one to ask, so rather
+// than hang (or guess) it's an error.
+func confirm(cmd *cobra.Command, question string) (bool, error) {
+
+	if !isTerminal(os.Stdin) {
+		return false, errors.New("stdin is not a terminal, so can't ask for confirmation; pass --yes to proceed regardless")
+	}
+
+	fmt.Fprintf(cmd.ErrOrStderr(), "%s [y/N] ", question)
+	line, err := readLine(cmd.InOrStdin())
+	if err != nil {
+		return false, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
+
+// readLine reads up to & including the next newline from r (or to EOF). It
+// reads a byte at a time, so as to leave the rest of r for whoever reads it
+// next (bulk input on stdin after the token, say).
+func readLine(r io.Reader) (string, error) {
+
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := r.Read(b)
+		if n == 1 {
+			line = append(line, b[0])
+			if b[0] == '\n' {
+				return string(line), nil
+			}
+		}
+		if err == io.EOF {
+			return string(line), nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// readToken reads the API token from the first line of stdin; if that's a
+// terminal, the user is prompted for it (without echoing it).
+func readToken(cmd *cobra.Command) (string, error) {
+
+	var line string
+	if isTerminal(os.Stdin) {
+		fmt.Fprint(cmd.ErrOrStderr(), "API token: ")
+		buf, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(cmd.ErrOrStderr())
+		if err != nil {
+			return "", err
+		}
+		line = string(buf)
+	} else {
+		var err error
+		line, err = readLine(cmd.InOrStdin())
+		if err != nil {
+			return "", err
+		}
+	}
+
+	token := strings.TrimSpace(line)
+	if token == "" {
+		return "", errors.New("--token - was given, but no API token was read from stdin")
+	}
+	return token, nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+// readLine mustn't consume anything past the line it returns.
+func TestReadLine(t *testing.T) {
+
+	r := strings.NewReader("user:0123\nurl,title\nhttps://example.com,Example\n")
+	line, err := readLine(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "user:0123\n" {
+		t.Errorf("got %q", line)
+	}
+	rest, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rest) != "url,title\nhttps://example.com,Example\n" {
+		t.Errorf("left %q", rest)
+	}
+
+	line, err = readLine(strings.NewReader("no newline"))
+	if err != nil || line != "no newline" {
+		t.Errorf("got %q, %v", line, err)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"github.com/tidwall/gjson"
+)
+
+// outputQuery is the GJSON path given by --query, if any; when set, JSON
+// output is replaced with whatever the query extracts from it.
+var outputQuery string
+
+// setQuery checks --query, if given. It applies only to JSON output, so the
+// command must offer a --format flag.
+func setQuery(cmd *cobra.Command) error {
+
+	query, err := cmd.Flags().GetString("query")
+	if err != nil {
+		return err
+	}
+	if query == "" {
+		return nil
+	}
+	if cmd.Flags().Lookup("format") == nil {
+		return fmt.Errorf("pin %s has no JSON output for --query to apply to", cmd.Name())
+	}
+	err = checkQuery(query)
+	if err != nil {
+		return err
+	}
+	outputQuery = query
+	return nil
+}
+
+// checkQuery catches the commonest mistakes in a GJSON path (gjson itself
+// simply finds nothing, which would be confusing).
+func checkQuery(query string) error {
+
+	closers := map[rune]rune{'(': ')', '[': ']', '{': '}'}
+	var open []rune
+	var quote rune
+	escaped := false
+	for _, r := range query {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"':
+			quote = r
+		case closers[r] != 0:
+			open = append(open, closers[r])
+		case r == ')' || r == ']' || r == '}':
+			if len(open) == 0 || open[len(open)-1] != r {
+				return fmt.Errorf("invalid --query %q: unexpected %q", query, r)
+			}
+			open = open[:len(open)-1]
+		}
+	}
+	if quote != 0 {
+		return fmt.Errorf("invalid --query %q: unterminated string", query)
+	}
+	if len(open) != 0 {
+		return fmt.Errorf("invalid --query %q: missing %q", query, open[len(open)-1])
+	}
+	return nil
+}
+
+// writeQueried writes the result of outputQuery against the JSON encoding of
+// v to w: strings are written bare, anything else as JSON.
+func writeQueried(w io.Writer, v interface{}) error {
+
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	result := gjson.GetBytes(buf, outputQuery)
+	if !result.Exists() {
+		return fmt.Errorf("--query %q matched nothing", outputQuery)
+	}
+
+	if result.Type == gjson.String {
+		_, err = fmt.Fprintln(w, result.String())
+		return err
+	}
+	var out bytes.Buffer
+	err = json.Indent(&out, []byte(result.Raw), "", "  ")
+	if err != nil {
+		return err
+	}
+	out.WriteByte('\n')
+	_, err = out.WriteTo(w)
+	return err
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sp1ff/gopin/pinboard"
+	"github.com/spf13/cobra"
+)
+
+// renamer renames tags, keeping track of the user's tag set as it goes so as
+// to notice when a rename would fold one tag into another.
+type renamer struct {
+	cmd    *cobra.Command
+	client *pinboard.Client
+	yes    bool
+	uses   map[string]uint64
+}
+
+// newRenamer returns a renamer; if fetch is false, the user's tags aren't
+// retrieved, so it can't notice merges, & should only be used with --yes.
+func newRenamer(cmd *cobra.Command, client *pinboard.Client, fetch bool) (*renamer, error) {
+
+	yes, err := cmd.Flags().GetBool("yes")
+	if err != nil {
+		return nil, err
+	}
+
+	uses := make(map[string]uint64)
+	if fetch {
+		tags, err := client.GetTags(cmd.Context())
+		if err != nil {
+			return nil, err
+		}
+		for _, tag := range tags {
+			uses[tag.Name] = tag.UseCount
+		}
+	}
+
+	return &renamer{cmd: cmd, client: client, yes: yes, uses: uses}, nil
+}
+
+// rename renames old to new, first asking the user if new already exists. It
+// returns false if the user declined.
+func (r *renamer) rename(old, new string) (bool, error) {
+
+	if _, ok := r.uses[new]; ok && new != old && !r.yes {
+		ok, err := confirm(r.cmd, fmt.Sprintf("Tag %q already exists; folding %q into it will merge %d bookmarks. Continue?",
+			new, old, r.uses[old]))
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+
+	err := r.client.RenameTag(r.cmd.Context(), old, new)
+	if err != nil {
+		return false, fmt.Errorf("failed to rename %q to %q: %w", old, new, err)
+	}
+
+	r.uses[new] += r.uses[old]
+	delete(r.uses, old)
+	return true, nil
+}
+
+// renameTagsFromFile performs the renames listed in the CSV file name, one
+// "old,new" pair per line. A failure on any one line doesn't stop the rest.
+func renameTagsFromFile(cmd *cobra.Command, r *renamer, name string) error {
+
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	cr := csv.NewReader(f)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = 2
+	cr.TrimLeadingSpace = true
+
+	p := startProgress(cmd, "Renaming tags", 0)
+	defer p.stop()
+
+	renamed, skipped := 0, 0
+	var errs []error
+	for {
+		p.set(renamed + skipped + len(errs))
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		var line int
+		if err != nil {
+			var pe *csv.ParseError
+			if errors.As(err, &pe) {
+				line = pe.Line
+			}
+			if failFast(cmd) {
+				return fmt.Errorf("%s:%d: %w", name, line, err)
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s:%d: %v\n", name, line, err)
+			errs = append(errs, err)
+			continue
+		}
+
+		line, _ = cr.FieldPos(0)
+		old, new := strings.TrimSpace(record[0]), strings.TrimSpace(record[1])
+		ok := false
+		if _, exists := r.uses[old]; !exists {
+			err = fmt.Errorf("no such tag %q", old)
+		} else {
+			ok, err = r.rename(old, new)
+		}
+		if err != nil {
+			if failFast(cmd) {
+				return fmt.Errorf("%s:%d: %w", name, line, err)
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s:%d: %v\n", name, line, err)
+			errs = append(errs, err)
+			continue
+		}
+		if !ok {
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s:%d: Skipped %q.\n", name, line, old)
+			skipped += 1
+			continue
+		}
+		report(cmd, "%s:%d: Renamed %q to %q.", name, line, old, new)
+		renamed += 1
+	}
+
+	p.stop()
+	total := renamed + skipped + len(errs)
+	fmt.Fprintf(cmd.OutOrStdout(), "Renamed %d of %d tags.\n", renamed, total)
+	if len(errs) != 0 {
+		return newBatchError(errs, "failed to rename %d of %d tags", len(errs), total)
+	}
+	return nil
+}
+
+// renameTagsByRegex renames every tag matching pattern, per the --replace
+// & --allow-merge flags, after previewing the renames & asking the user.
+func renameTagsByRegex(cmd *cobra.Command, r *renamer, pattern string) error {
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid --regex: %v", err)
+	}
+	repl, err := cmd.Flags().GetString("replace")
+	if err != nil {
+		return err
+	}
+	allowMerge, err := cmd.Flags().GetBool("allow-merge")
+	if err != nil {
+		return err
+	}
+
+	var olds []string
+	for name := range r.uses {
+		if re.MatchString(name) {
+			olds = append(olds, name)
+		}
+	}
+	sort.Strings(olds)
+
+	type rename struct{ old, new string }
+	var renames []rename
+	targets := make(map[string]bool)
+	for _, old := range olds {
+		new := re.ReplaceAllString(old, repl)
+		if new == old {
+			continue
+		}
+		if err := pinboard.ValidateTag(new); err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Skipping %q: %v.\n", old, err)
+			continue
+		}
+		// new collides either with a tag that exists now, or with the result
+		// of an earlier rename in this batch
+		_, exists := r.uses[new]
+		if (exists || targets[new]) && !allowMerge {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Skipping %q: %q already exists (pass --allow-merge to fold them).\n", old, new)
+			continue
+		}
+		targets[new] = true
+		renames = append(renames, rename{old, new})
+	}
+
+	if len(renames) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "No tags to rename.")
+		return nil
+	}
+
+	t := table{headers: []string{"Old", "New"}}
+	for _, rn := range renames {
+		t.rows = append(t.rows, []string{rn.old, rn.new})
+	}
+	err = t.write(cmd.OutOrStdout())
+	if err != nil {
+		return err
+	}
+
+	if !r.yes {
+		ok, err := confirm(cmd, fmt.Sprintf("Rename %d tags?", len(renames)))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintln(cmd.ErrOrStderr(), "Not renamed.")
+			return nil
+		}
+		// the user has seen (and approved) any merges in the preview
+		r.yes = true
+	}
+
+	p := startProgress(cmd, "Renaming tags", len(renames))
+	defer p.stop()
+
+	var errs []error
+	for i, rn := range renames {
+		p.set(i)
+		_, err = r.rename(rn.old, rn.new)
+		if err != nil {
+			if failFast(cmd) {
+				return err
+			}
+			fmt.Fprintln(cmd.ErrOrStderr(), err)
+			errs = append(errs, err)
+			continue
+		}
+		report(cmd, "Renamed %q to %q.", rn.old, rn.new)
+	}
+	p.stop()
+
+	if len(errs) != 0 {
+		return newBatchError(errs, "failed to rename %d of %d tags", len(errs), len(renames))
+	}
+	return nil
+}
+
+// mergeTags folds each of the tags named in args into that given by --into.
+func mergeTags(cmd *cobra.Command, args []string) error {
+
+	into, err := cmd.Flags().GetString("into")
+	if err != nil {
+		return err
+	}
+	err = validateTags(append([]string{into}, args...))
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient(cmd)
+	if err != nil {
+		return err
+	}
+	defer invalidateTagsCache(cmd)
+	r, err := newRenamer(cmd, client, true)
+	if err != nil {
+		return err
+	}
+
+	var sources []string
+	seen := make(map[string]bool)
+	uses := uint64(0)
+	for _, tag := range args {
+		switch _, exists := r.uses[tag]; {
+		case seen[tag]:
+			continue
+		case tag == into:
+			fmt.Fprintf(cmd.ErrOrStderr(), "Skipping %q: it's the tag being merged into.\n", tag)
+		case !exists:
+			fmt.Fprintf(cmd.ErrOrStderr(), "Skipping %q: no such tag.\n", tag)
+		default:
+			sources = append(sources, tag)
+			uses += r.uses[tag]
+		}
+		seen[tag] = true
+	}
+	if len(sources) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "No tags to merge.")
+		return nil
+	}
+
+	if !r.yes {
+		ok, err := confirm(cmd, fmt.Sprintf("Merge %d tags (used %d times) into %q?", len(sources), uses, into))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintln(cmd.ErrOrStderr(), "Not merged.")
+			return nil
+		}
+		r.yes = true
+	}
+
+	p := startProgress(cmd, "Merging tags", len(sources))
+	defer p.stop()
+
+	var errs []error
+	for i, tag := range sources {
+		p.set(i)
+		_, err = r.rename(tag, into)
+		if err != nil {
+			if failFast(cmd) {
+				return err
+			}
+			fmt.Fprintln(cmd.ErrOrStderr(), err)
+			errs = append(errs, err)
+			continue
+		}
+		report(cmd, "Merged %q into %q.", tag, into)
+	}
+	p.stop()
+
+	if len(errs) != 0 {
+		return newBatchError(errs, "failed to merge %d of %d tags", len(errs), len(sources))
+	}
+	return nil
+}
+
+var mergeTagsCmd = &cobra.Command{
+	Use:   "merge-tags --into TAG TAG...",
+	Short: "Fold several tags into one",
+	Long: `Fold several tags into one.
+
+Each of the given tags is renamed to the tag given by --into, merging them;
+as that can't be undone, pin asks first (unless given --yes).`,
+	Args:              cobra.MinimumNArgs(1),
+	RunE:              mergeTags,
+	ValidArgsFunction: completeTags,
+}
+
+func init() {
+	mergeTagsCmd.Flags().String("into", "", "The tag into which to merge the others (required)")
+	mergeTagsCmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation")
+	mergeTagsCmd.MarkFlagRequired("into")
+	mergeTagsCmd.RegisterFlagCompletionFunc("into", completeTags)
+}
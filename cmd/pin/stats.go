@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/sp1ff/gopin/pinboard"
+	"github.com/spf13/cobra"
+)
+
+// tagStats summarises the use of the user's tags.
+type tagStats struct {
+	Tags   int     `json:"tags"`
+	Uses   uint64  `json:"uses"`
+	Mean   float64 `json:"mean_uses"`
+	Median float64 `json:"median_uses"`
+	// MostUsed is nil if there are no tags; ties go to the first by name
+	MostUsed *pinboard.Tag `json:"most_used"`
+	// Orphans is the number of tags used exactly once
+	Orphans int `json:"orphans"`
+}
+
+func computeTagStats(tags []pinboard.Tag) tagStats {
+
+	stats := tagStats{Tags: len(tags), Uses: sumUses(tags)}
+	if len(tags) == 0 {
+		return stats
+	}
+
+	counts := make([]uint64, len(tags))
+	for i, tag := range tags {
+		counts[i] = tag.UseCount
+		if tag.UseCount == 1 {
+			stats.Orphans += 1
+		}
+		if stats.MostUsed == nil || tag.UseCount > stats.MostUsed.UseCount ||
+			(tag.UseCount == stats.MostUsed.UseCount && tag.Name < stats.MostUsed.Name) {
+			stats.MostUsed = &tags[i]
+		}
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i] < counts[j] })
+
+	stats.Mean = float64(stats.Uses) / float64(len(tags))
+	n := len(counts)
+	if n%2 == 1 {
+		stats.Median = float64(counts[n/2])
+	} else {
+		stats.Median = float64(counts[n/2-1]+counts[n/2]) / 2
+	}
+	return stats
+}
+
+func stats(cmd *cobra.Command, args []string) error {
+
+	format, err := getFormat(cmd, "table", "json")
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient(cmd)
+	if err != nil {
+		return err
+	}
+	tags, err := fetchTags(cmd, client)
+	if err != nil {
+		return err
+	}
+
+	stats := computeTagStats(tags)
+	if format == "json" {
+		return writeJSON(cmd.OutOrStdout(), stats)
+	}
+	if stats.Tags == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "You have no tags yet.")
+		return nil
+	}
+
+	t := table{headers: []string{"Statistic", "Value"}, right: []bool{false, true}}
+	t.rows = [][]string{
+		{"Tags", strconv.Itoa(stats.Tags)},
+		{"Uses", strconv.FormatUint(stats.Uses, 10)},
+		{"Mean uses per tag", strconv.FormatFloat(stats.Mean, 'f', 2, 64)},
+		{"Median uses per tag", strconv.FormatFloat(stats.Median, 'f', 1, 64)},
+		{"Most-used tag", fmt.Sprintf("%s (%d)", stats.MostUsed.Name, stats.MostUsed.UseCount)},
+		{"Tags used once", strconv.Itoa(stats.Orphans)},
+	}
+	return t.write(cmd.OutOrStdout())
+}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Summarise how your tags are used",
+	Args:  cobra.NoArgs,
+	RunE:  stats,
+}
+
+func init() {
+	statsCmd.Flags().StringP("format", "f", "table", "Output format: table|json")
+	statsCmd.Flags().Bool("no-cache", false, "Neither read nor update the local cache of your tags")
+	statsCmd.Flags().Bool("refresh", false, "Re-fetch your tags even if the cached copy is current")
+}
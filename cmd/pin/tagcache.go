@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sp1ff/gopin/pinboard"
+	"github.com/spf13/cobra"
+)
+
+// The user's tags are cached along with the time their bookmarks last
+// changed (per posts/update); the cached tags are used for as long as that
+// time doesn't change, up to tagsCacheTTL. After that, they're re-fetched
+// conditionally, per the validators Pinboard sent with them.
+type tagsCacheEntry struct {
+	Updated    time.Time           `json:"updated"`
+	Validators pinboard.Validators `json:"validators"`
+	Tags       []pinboard.Tag      `json:"tags"`
+}
+
+const tagsCacheTTL = 24 * time.Hour
+
+func tagsCacheKey(cmd *cobra.Command) string {
+	return cacheKey("tags", cmd.Flag("token").Value.String())
+}
+
+// fetchTags retrieves the user's tags, from the cache if possible, unless
+// cmd's --no-cache or --refresh say otherwise.
+func fetchTags(cmd *cobra.Command, client *pinboard.Client) ([]pinboard.Tag, error) {
+
+	noCache, err := cmd.Flags().GetBool("no-cache")
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := cmd.Flags().GetBool("refresh")
+	if err != nil {
+		return nil, err
+	}
+	if noCache {
+		return client.GetTags(cmd.Context())
+	}
+
+	updated, err := client.LastUpdate(cmd.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	key := tagsCacheKey(cmd)
+	var entry tagsCacheEntry
+	if !refresh && readCache(key, tagsCacheTTL, &entry) && entry.Updated.Equal(updated) {
+		return entry.Tags, nil
+	}
+
+	var v pinboard.Validators
+	if !refresh && readCache(key, 0, &entry) {
+		v = entry.Validators
+	}
+	tags, err := client.GetTags(pinboard.WithValidators(cmd.Context(), &v))
+	if errors.Is(err, pinboard.ErrNotModified) {
+		log.Debug("Pinboard reports the cached tags are unchanged.")
+		tags, err = entry.Tags, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	writeCache(key, tagsCacheEntry{Updated: updated, Validators: v, Tags: tags})
+	return tags, nil
+}
+
+// invalidateTagsCache is to be called after modifying the user's tags; it
+// drops the names cached for completion, too.
+func invalidateTagsCache(cmd *cobra.Command) {
+	removeCache(tagsCacheKey(cmd))
+	removeCache(completionTagsCacheKey(cmd))
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// newTagsCommand returns a command with the flags consulted by fetchTags.
+func newTagsCommand(args ...string) *cobra.Command {
+
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().String("token", testToken, "")
+	cmd.Flags().Bool("no-cache", false, "")
+	cmd.Flags().Bool("refresh", false, "")
+	cmd.Flags().Parse(args)
+	cmd.SetContext(context.Background())
+	return cmd
+}
+
+func TestFetchTagsCached(t *testing.T) {
+
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	calls := 0
+	tags := map[string]string{"go": "3", "emacs": "7"}
+	client := newTestClient(t, newTestServer(t, tagsHandler(tags, &calls)))
+
+	for i, test := range []struct {
+		args  []string
+		calls int
+	}{
+		{nil, 1},
+		// the update time hasn't changed, so the cached tags are used
+		{nil, 1},
+		{[]string{"--refresh"}, 2},
+		{[]string{"--no-cache"}, 3},
+	} {
+		got, err := fetchTags(newTagsCommand(test.args...), client)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 2 {
+			t.Errorf("%d: got %v", i, got)
+		}
+		if calls != test.calls {
+			t.Errorf("%d %v: tags/get has been called %d times; want %d", i, test.args, calls, test.calls)
+		}
+	}
+
+	invalidateTagsCache(newTagsCommand())
+	_, err := fetchTags(newTagsCommand(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 4 {
+		t.Errorf("tags/get wasn't called after the cache was invalidated")
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/sp1ff/gopin/pinboard"
+)
+
+// tagNode is one level of a hierarchy of tags: e.g. the tags "work/admin" &
+// "work/clients/acme" give a node "work", with children "admin" & "clients",
+// the latter having a child "acme".
+type tagNode struct {
+	uses     uint64 // the total for this node & all beneath it
+	children map[string]*tagNode
+}
+
+// isHierarchical reports whether any of tags contains delim.
+func isHierarchical(tags []pinboard.Tag, delim string) bool {
+	for _, tag := range tags {
+		if strings.Contains(tag.Name, delim) {
+			return true
+		}
+	}
+	return false
+}
+
+func buildTagTree(tags []pinboard.Tag, delim string) *tagNode {
+	root := &tagNode{children: make(map[string]*tagNode)}
+	for _, tag := range tags {
+		node := root
+		node.uses += tag.UseCount
+		for _, part := range strings.Split(tag.Name, delim) {
+			child, ok := node.children[part]
+			if !ok {
+				child = &tagNode{children: make(map[string]*tagNode)}
+				node.children[part] = child
+			}
+			child.uses += tag.UseCount
+			node = child
+		}
+	}
+	return root
+}
+
+// writeTagsTree writes o's tags as a tree, split on delim, with the children
+// of each node ordered by sortTags.
+func writeTagsTree(w io.Writer, o *tagsOutput, delim string, sortTags func([]pinboard.Tag)) error {
+
+	root := buildTagTree(o.tags, delim)
+
+	t := table{headers: []string{"Tag", "Use Count"}, right: []bool{false, true, true}, fit: o.fit, noColor: o.noColor}
+	if o.percent {
+		t.headers = append(t.headers, "%")
+	}
+	var uses []uint64
+	maxUses := uint64(0)
+
+	var walk func(node *tagNode, depth int)
+	walk = func(node *tagNode, depth int) {
+		// Sort each level as a list of (name, total) tags
+		level := make([]pinboard.Tag, 0, len(node.children))
+		for name, child := range node.children {
+			level = append(level, pinboard.Tag{Name: name, UseCount: child.uses})
+		}
+		sortTags(level)
+		for _, tag := range level {
+			row := []string{truncate(strings.Repeat("  ", depth)+tag.Name, o.maxWidth), strconv.FormatUint(tag.UseCount, 10)}
+			if o.percent {
+				row = append(row, fmt.Sprintf("%5.1f", o.percentOf(tag)))
+			}
+			t.rows = append(t.rows, row)
+			uses = append(uses, tag.UseCount)
+			if tag.UseCount > maxUses {
+				maxUses = tag.UseCount
+			}
+			walk(node.children[tag.Name], depth+1)
+		}
+	}
+	walk(root, 0)
+
+	t.color = func(i, j int) *color.Color {
+		if j != 1 {
+			return nil
+		}
+		return useColor(uses[i], maxUses)
+	}
+	return t.write(w)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildTagTree(t *testing.T) {
+
+	root := buildTagTree(tagsOf("work/admin", 2, "work/clients/acme", 4, "work/clients/beta", 1, "work", 1, "go", 3), "/")
+	for _, test := range []struct {
+		path string
+		uses uint64
+	}{
+		{"", 11},
+		{"go", 3},
+		{"work", 8},
+		{"work/admin", 2},
+		{"work/clients", 5},
+		{"work/clients/acme", 4},
+	} {
+		node := root
+		if test.path != "" {
+			for _, part := range strings.Split(test.path, "/") {
+				node = node.children[part]
+				if node == nil {
+					t.Fatalf("no node for %q", test.path)
+				}
+			}
+		}
+		if node.uses != test.uses {
+			t.Errorf("%q: got %d uses; want %d", test.path, node.uses, test.uses)
+		}
+	}
+}
+
+func TestGetTagsTree(t *testing.T) {
+
+	srv := newTestServer(t, tagsHandler(map[string]string{
+		"work:admin": "2", "work:clients:acme": "4", "work:clients:beta": "1", "go": "3",
+	}, nil))
+
+	stdout, _, err := runPin(t, srv, "get-tags", "--no-cache", "--tree", "--tag-delimiter", ":", "--sort-by", "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `| Tag       | Use Count |
++-----------+-----------+
+| go        |         3 |
+| work      |         7 |
+|   admin   |         2 |
+|   clients |         5 |
+|     acme  |         4 |
+|     beta  |         1 |
++-----------+-----------+
+Total: 4 tags, 10 uses
+`
+	if stdout != want {
+		t.Errorf("got\n%s\nwant\n%s", stdout, want)
+	}
+}
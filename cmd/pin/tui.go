@@ -0,0 +1,334 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/sp1ff/gopin/pinboard"
+	"github.com/spf13/cobra"
+)
+
+// tagBrowser is the state of the tui command.
+type tagBrowser struct {
+	cmd    *cobra.Command
+	client *pinboard.Client
+	dryRun bool
+
+	tags    []pinboard.Tag
+	shown   []pinboard.Tag // tags, filtered & sorted
+	sortBy  string
+	desc    bool
+	changed bool
+	busy    bool
+
+	app    *tview.Application
+	pages  *tview.Pages
+	header *tview.TextView
+	filter *tview.InputField
+	table  *tview.Table
+	status *tview.TextView
+}
+
+const tuiHelp = "/ filter  s sort  o order  r rename  d delete  q quit"
+
+func newTagBrowser(cmd *cobra.Command, client *pinboard.Client, dryRun bool, tags []pinboard.Tag) *tagBrowser {
+
+	b := &tagBrowser{
+		cmd:    cmd,
+		client: client,
+		dryRun: dryRun,
+		tags:   tags,
+		sortBy: "count",
+		desc:   true,
+		app:    tview.NewApplication(),
+		pages:  tview.NewPages(),
+		header: tview.NewTextView(),
+		filter: tview.NewInputField().SetLabel("Filter: "),
+		table:  tview.NewTable().SetSelectable(true, false).SetFixed(1, 0),
+		status: tview.NewTextView(),
+	}
+
+	b.filter.SetChangedFunc(func(string) { b.refresh() })
+	b.filter.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEscape {
+			b.filter.SetText("")
+		}
+		b.app.SetFocus(b.table)
+	})
+	b.table.SetInputCapture(b.onKey)
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(b.header, 1, 0, false).
+		AddItem(b.filter, 1, 0, false).
+		AddItem(b.table, 0, 1, true).
+		AddItem(b.status, 1, 0, false).
+		AddItem(tview.NewTextView().SetText(tuiHelp), 1, 0, false)
+	b.pages.AddPage("main", layout, true, true)
+	b.app.SetRoot(b.pages, true).SetFocus(b.table)
+
+	b.refresh()
+	return b
+}
+
+// refresh redraws the table after any change to the tags, the filter or the
+// sort order, keeping the same tag selected where possible.
+func (b *tagBrowser) refresh() {
+
+	current, _ := b.selected()
+
+	filter := strings.ToLower(b.filter.GetText())
+	b.shown = b.shown[:0]
+	for _, tag := range b.tags {
+		if strings.Contains(strings.ToLower(tag.Name), filter) {
+			b.shown = append(b.shown, tag)
+		}
+	}
+
+	sortTags(b.shown, b.sortBy, b.desc, true)
+
+	b.table.Clear()
+	b.table.SetCell(0, 0, tview.NewTableCell("Use Count").SetSelectable(false).SetAttributes(tcell.AttrBold))
+	b.table.SetCell(0, 1, tview.NewTableCell("Tag").SetSelectable(false).SetAttributes(tcell.AttrBold).SetExpansion(1))
+	row := 1
+	for i, tag := range b.shown {
+		b.table.SetCell(i+1, 0, tview.NewTableCell(strconv.FormatUint(tag.UseCount, 10)).SetAlign(tview.AlignRight))
+		b.table.SetCell(i+1, 1, tview.NewTableCell(tview.Escape(tag.Name)))
+		if tag.Name == current.Name {
+			row = i + 1
+		}
+	}
+	b.table.Select(row, 0)
+
+	order := "ascending"
+	if b.desc {
+		order = "descending"
+	}
+	b.header.SetText(fmt.Sprintf("%d of %d tags, sorted by %s (%s)", len(b.shown), len(b.tags), b.sortBy, order))
+}
+
+func (b *tagBrowser) selected() (pinboard.Tag, bool) {
+	row, _ := b.table.GetSelection()
+	if row < 1 || row > len(b.shown) {
+		return pinboard.Tag{}, false
+	}
+	return b.shown[row-1], true
+}
+
+func (b *tagBrowser) useCount(name string) (uint64, bool) {
+	for _, tag := range b.tags {
+		if tag.Name == name {
+			return tag.UseCount, true
+		}
+	}
+	return 0, false
+}
+
+func (b *tagBrowser) onKey(ev *tcell.EventKey) *tcell.EventKey {
+
+	if b.busy {
+		return nil
+	}
+	if ev.Key() == tcell.KeyEscape {
+		b.app.Stop()
+		return nil
+	}
+	if ev.Key() != tcell.KeyRune {
+		return ev
+	}
+
+	switch ev.Rune() {
+	case 'q':
+		b.app.Stop()
+	case '/':
+		b.app.SetFocus(b.filter)
+	case 's':
+		switch b.sortBy {
+		case "count":
+			b.sortBy = "name"
+		case "name":
+			b.sortBy = "length"
+		default:
+			b.sortBy = "count"
+		}
+		b.refresh()
+	case 'o':
+		b.desc = !b.desc
+		b.refresh()
+	case 'r':
+		if tag, ok := b.selected(); ok {
+			b.promptRename(tag)
+		}
+	case 'd':
+		if tag, ok := b.selected(); ok {
+			b.confirm(fmt.Sprintf("Delete %q (used %d times)?", tag.Name, tag.UseCount), func() {
+				b.delete(tag.Name)
+			})
+		}
+	default:
+		return ev
+	}
+	return nil
+}
+
+// dialog shows p in the middle of the screen, over the table.
+func (b *tagBrowser) dialog(p tview.Primitive, width, height int) {
+	b.pages.AddPage("dialog", tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(p, height, 0, true).
+			AddItem(nil, 0, 1, false), width, 0, true).
+		AddItem(nil, 0, 1, false), true, true)
+}
+
+func (b *tagBrowser) closeDialog() {
+	b.pages.RemovePage("dialog")
+	b.app.SetFocus(b.table)
+}
+
+// confirm asks question, calling yes if the user agrees.
+func (b *tagBrowser) confirm(question string, yes func()) {
+	modal := tview.NewModal().
+		SetText(question).
+		AddButtons([]string{"No", "Yes"}).
+		SetDoneFunc(func(_ int, label string) {
+			b.pages.RemovePage("modal")
+			b.app.SetFocus(b.table)
+			if label == "Yes" {
+				yes()
+			}
+		})
+	b.pages.AddPage("modal", modal, true, true)
+}
+
+func (b *tagBrowser) promptRename(tag pinboard.Tag) {
+
+	input := tview.NewInputField().SetLabel("New name: ").SetText(tag.Name)
+	input.SetBorder(true).SetTitle(fmt.Sprintf(" Rename %q ", tag.Name))
+	input.SetDoneFunc(func(key tcell.Key) {
+		b.closeDialog()
+		new := strings.TrimSpace(input.GetText())
+		if key != tcell.KeyEnter || new == "" || new == tag.Name {
+			return
+		}
+		if _, ok := b.useCount(new); ok {
+			b.confirm(fmt.Sprintf("Tag %q already exists; folding %q into it will merge %d bookmarks. Continue?",
+				new, tag.Name, tag.UseCount), func() {
+				b.rename(tag.Name, new)
+			})
+			return
+		}
+		b.rename(tag.Name, new)
+	})
+	b.dialog(input, 60, 3)
+}
+
+// run carries out a change to the user's tags in the background, calling
+// apply to make the same change to the browser's copy of them on success.
+func (b *tagBrowser) run(doing string, change func() error, done string, apply func()) {
+
+	b.busy = true
+	b.status.SetText(doing)
+	go func() {
+		err := change()
+		b.app.QueueUpdateDraw(func() {
+			b.busy = false
+			if err != nil {
+				b.status.SetText(err.Error())
+				return
+			}
+			apply()
+			b.changed = true
+			if b.dryRun {
+				done = "(dry run) " + done
+			}
+			b.status.SetText(done)
+			b.refresh()
+		})
+	}()
+}
+
+func (b *tagBrowser) rename(old, new string) {
+	b.run(fmt.Sprintf("Renaming %q to %q...", old, new),
+		func() error {
+			err := b.client.RenameTag(b.cmd.Context(), old, new)
+			if err != nil {
+				return fmt.Errorf("failed to rename %q to %q: %w", old, new, err)
+			}
+			return nil
+		},
+		fmt.Sprintf("Renamed %q to %q.", old, new),
+		func() {
+			uses, _ := b.useCount(old)
+			b.tags = filterTags(b.tags, func(tag pinboard.Tag) bool { return tag.Name != old })
+			for i := range b.tags {
+				if b.tags[i].Name == new {
+					b.tags[i].UseCount += uses
+					return
+				}
+			}
+			b.tags = append(b.tags, pinboard.Tag{Name: new, UseCount: uses})
+		})
+}
+
+func (b *tagBrowser) delete(name string) {
+	b.run(fmt.Sprintf("Deleting %q...", name),
+		func() error {
+			err := b.client.DeleteTag(b.cmd.Context(), name)
+			if err != nil {
+				return fmt.Errorf("failed to delete %q: %w", name, err)
+			}
+			return nil
+		},
+		fmt.Sprintf("Deleted %q.", name),
+		func() {
+			b.tags = filterTags(b.tags, func(tag pinboard.Tag) bool { return tag.Name != name })
+		})
+}
+
+func tui(cmd *cobra.Command, args []string) error {
+
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		return errors.New("pin tui needs a terminal; try get-tags instead")
+	}
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient(cmd)
+	if err != nil {
+		return err
+	}
+	tags, err := client.GetTags(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	b := newTagBrowser(cmd, client, dryRun, tags)
+	go func() {
+		<-cmd.Context().Done()
+		b.app.Stop()
+	}()
+	err = b.app.Run()
+	if b.changed {
+		invalidateTagsCache(cmd)
+	}
+	return err
+}
+
+var tuiCmd = &cobra.Command{
+	Use:   "tui",
+	Short: "Browse your tags interactively",
+	Long: `Browse your tags interactively.
+
+Type '/' to filter the tags, 's' to change the sort key & 'o' the order, and
+'r' or 'd' to rename or delete the selected tag.`,
+	Args: cobra.NoArgs,
+	RunE: tui,
+}
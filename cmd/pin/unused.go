@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/sp1ff/gopin/pinboard"
+	"github.com/spf13/cobra"
+)
+
+func findUnused(cmd *cobra.Command, args []string) error {
+
+	maxCount, err := cmd.Flags().GetUint64("max-count")
+	if err != nil {
+		return err
+	}
+	format, err := getFormat(cmd, "table", "json", "ndjson")
+	if err != nil {
+		return err
+	}
+	del, err := cmd.Flags().GetBool("delete")
+	if err != nil {
+		return err
+	}
+	yes, err := cmd.Flags().GetBool("yes")
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient(cmd)
+	if err != nil {
+		return err
+	}
+	tags, err := fetchTags(cmd, client)
+	if err != nil {
+		return err
+	}
+	allUses := sumUses(tags)
+	tags = filterTags(tags, func(tag pinboard.Tag) bool { return tag.UseCount <= maxCount })
+	sort.Sort(useAsc(tags))
+
+	out := &tagsOutput{tags: tags, allUses: allUses}
+	switch format {
+	case "json":
+		err = writeTagsJSON(cmd.OutOrStdout(), out)
+	case "ndjson":
+		err = writeTagsNDJSON(cmd.OutOrStdout(), out)
+	default:
+		if len(tags) == 0 {
+			fmt.Fprintf(cmd.OutOrStdout(), "No tags are used %s or fewer times.\n", timesString(maxCount))
+			return nil
+		}
+		err = writeTagsTable(cmd.OutOrStdout(), out)
+	}
+	if err != nil || !del || len(tags) == 0 {
+		return err
+	}
+
+	if !yes {
+		ok, err := confirm(cmd, fmt.Sprintf("Delete these %d tags?", len(tags)))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintln(cmd.ErrOrStderr(), "Not deleted.")
+			return nil
+		}
+	}
+
+	defer invalidateTagsCache(cmd)
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	return deleteTagList(cmd, client, names)
+}
+
+func timesString(n uint64) string {
+	if n == 1 {
+		return "once"
+	}
+	return fmt.Sprintf("%d", n)
+}
+
+var findUnusedCmd = &cobra.Command{
+	Use:   "find-unused",
+	Short: "List rarely-used tags, optionally deleting them",
+	Long: `List the tags used on at most --max-count bookmarks: candidates for
+deletion, or for merging into others.
+
+With --delete, the tags are then deleted, once confirmed (unless given
+--yes).`,
+	Args: cobra.NoArgs,
+	RunE: findUnused,
+}
+
+func init() {
+	findUnusedCmd.Flags().Uint64("max-count", 1, "List tags used at most this many times")
+	findUnusedCmd.Flags().StringP("format", "f", "table", "Output format: table|json|ndjson")
+	findUnusedCmd.Flags().Bool("delete", false, "Delete the tags listed")
+	findUnusedCmd.Flags().BoolP("yes", "y", false, "Don't ask for confirmation before deleting")
+	findUnusedCmd.Flags().Bool("no-cache", false, "Neither read nor update the local cache of your tags")
+	findUnusedCmd.Flags().Bool("refresh", false, "Re-fetch your tags even if the cached copy is current")
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/spf13/cobra"
+)
+
+// Set at build time via -ldflags "-X main.version=... -X main.commit=..."
+var (
+	version string
+	commit  string
+)
+
+// buildVersion returns the version & commit of this build, falling back to
+// the module build info when they weren't supplied through the linker.
+func buildVersion() (string, string) {
+	v, c := version, commit
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if v == "" {
+			v = bi.Main.Version
+		}
+		for _, s := range bi.Settings {
+			if c == "" && s.Key == "vcs.revision" {
+				c = s.Value
+			}
+		}
+	}
+	if v == "" {
+		v = "(devel)"
+	}
+	if c == "" {
+		c = "unknown"
+	}
+	return v, c
+}
+
+func versionString() string {
+	v, c := buildVersion()
+	return fmt.Sprintf("%s (commit %s, %s)", v, c, runtime.Version())
+}
+
+func userAgent() string {
+	v, _ := buildVersion()
+	return fmt.Sprintf("gopin/%s (+https://github.com/sp1ff/gopin)", v)
+}
+
+var versionCmd = &cobra.Command{
+	Use:         "version",
+	Short:       "Print the version of this tool",
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{annotationNoToken: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Fprintf(cmd.OutOrStdout(), "pin %s\n", versionString())
+	},
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// maskToken renders token with all but the first & last few characters of
+// its secret part replaced by asterisks.
+func maskToken(token string) string {
+	i := strings.LastIndex(token, ":")
+	user, secret := token[:i+1], token[i+1:]
+	if len(secret) < 12 {
+		return user + strings.Repeat("*", len(secret))
+	}
+	return user + secret[:4] + strings.Repeat("*", len(secret)-8) + secret[len(secret)-4:]
+}
+
+func whoami(cmd *cobra.Command, args []string) error {
+
+	offline, err := cmd.Flags().GetBool("offline")
+	if err != nil {
+		return err
+	}
+
+	token := cmd.Flag("token").Value.String()
+	i := strings.Index(token, ":")
+	if i <= 0 {
+		return fmt.Errorf("malformed API token %q; expected user:HEX", maskToken(token))
+	}
+
+	if !offline {
+		client, err := newClient(cmd)
+		if err != nil {
+			return err
+		}
+		_, err = client.Secret(cmd.Context())
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "User:  %s\n", token[:i])
+	fmt.Fprintf(cmd.OutOrStdout(), "Token: %s\n", maskToken(token))
+	if offline {
+		fmt.Fprintln(cmd.OutOrStdout(), "(not verified)")
+	}
+	return nil
+}
+
+var whoamiCmd = &cobra.Command{
+	Use:   "whoami",
+	Short: "Show the account whose API token is in use",
+	Long: `Show the account whose API token is in use.
+
+The token is checked against Pinboard (unless --offline is given), so this
+is also a quick way to confirm that it's valid.`,
+	Args: cobra.NoArgs,
+	RunE: whoami,
+}
+
+func init() {
+	whoamiCmd.Flags().Bool("offline", false, "Don't check the token with Pinboard")
+}
@@ -0,0 +1,598 @@
+// Package pinboard is a client for the pinboard.in API (v1).
+package pinboard
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultBaseURL   = "https://api.pinboard.in/v1/"
+	defaultUserAgent = "gopin (+https://github.com/sp1ff/gopin)"
+)
+
+// Pinboard asks for at least three seconds between calls, and more for some
+// methods.
+const defaultRateInterval = 3 * time.Second
+
+// Methods that modify the user's data, & so aren't sent in dry-run mode
+var mutatingMethods = map[string]bool{
+	"posts/add":    true,
+	"posts/delete": true,
+	"tags/delete":  true,
+	"tags/rename":  true,
+}
+
+var methodIntervals = map[string]time.Duration{
+	"posts/all":    5 * time.Minute,
+	"posts/recent": time.Minute,
+}
+
+// The largest response body read, absent WithMaxResponseSize; posts/all
+// returns every bookmark at once, so is allowed rather more.
+const defaultMaxResponseSize = 8 << 20
+
+var methodMaxResponseSizes = map[string]int64{
+	"posts/all": 512 << 20,
+}
+
+// newHTTPClient returns the *http.Client used when the caller doesn't supply
+// one; all requests go to the same host, so keep a few connections warm.
+// Requests go through any proxy named by $HTTPS_PROXY &c.
+func newHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	transport.MaxIdleConns = 10
+	transport.MaxIdleConnsPerHost = 10
+	transport.IdleConnTimeout = 90 * time.Second
+	return &http.Client{Transport: transport}
+}
+
+// Client issues requests to the Pinboard API on behalf of a single user. A
+// Client is safe for concurrent use, and should be re-used across requests.
+type Client struct {
+	token      string
+	baseURL    string
+	httpClient *http.Client
+	userAgent  string
+	timeout    time.Duration
+	maxRetries int
+	retryCap   time.Duration
+	// Whether requests that time out are retried, too
+	retryTimeouts bool
+	dryRun        bool
+	proxy         *url.URL
+	insecure      bool
+	trace         io.Writer
+	maxSize       int64
+
+	// Rate limiting state: interval is the minimum time between any two
+	// requests, last the time of the most recent request, & lastByMethod
+	// that of the most recent request for each method in methodIntervals.
+	mu           sync.Mutex
+	interval     time.Duration
+	last         time.Time
+	lastByMethod map[string]time.Time
+}
+
+// Option configures a Client at construction time.
+type Option func(*Client) error
+
+// WithHTTPClient makes the Client issue its requests through hc.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) error {
+		c.httpClient = hc
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with each request.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) error {
+		c.userAgent = ua
+		return nil
+	}
+}
+
+// WithTimeout bounds the time taken by each request to d, not counting any
+// wait imposed by the rate limiter; zero means no limit beyond that imposed by
+// the caller's context.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) error {
+		if d < 0 {
+			return fmt.Errorf("invalid timeout %v", d)
+		}
+		c.timeout = d
+		return nil
+	}
+}
+
+// WithRateInterval sets the minimum time between successive requests
+// (default three seconds); zero disables rate limiting altogether, including
+// the longer intervals Pinboard requires for posts/all & posts/recent.
+func WithRateInterval(d time.Duration) Option {
+	return func(c *Client) error {
+		if d < 0 {
+			return fmt.Errorf("invalid rate interval %v", d)
+		}
+		c.interval = d
+		return nil
+	}
+}
+
+// WithMaxRetries sets the number of times a request failing with a 429 or 5xx
+// status will be retried (default three, at most ten).
+func WithMaxRetries(n int) Option {
+	return func(c *Client) error {
+		if n < 0 || n > maxMaxRetries {
+			return fmt.Errorf("invalid retry count %d; expected 0-%d", n, maxMaxRetries)
+		}
+		c.maxRetries = n
+		return nil
+	}
+}
+
+// WithRetryAfterCap bounds the wait before retrying a request at d (default
+// one minute): a longer computed backoff is shortened, while if Pinboard asks
+// (via Retry-After) for a longer wait, the request fails instead. Zero
+// means no limit.
+func WithRetryAfterCap(d time.Duration) Option {
+	return func(c *Client) error {
+		if d < 0 {
+			return fmt.Errorf("invalid retry-after cap %v", d)
+		}
+		c.retryCap = d
+		return nil
+	}
+}
+
+// WithRetryOnTimeout makes requests that get no response in time (per
+// WithTimeout) subject to retry, like those failing with a 429 or 5xx status.
+// It's off by default, as it multiplies the time taken to give up on an
+// unresponsive server.
+func WithRetryOnTimeout(retry bool) Option {
+	return func(c *Client) error {
+		c.retryTimeouts = retry
+		return nil
+	}
+}
+
+// WithDryRun puts the Client into dry-run mode: requests that would modify
+// the user's data are logged, but not sent, and are treated as having
+// succeeded. Read-only requests are sent as usual.
+func WithDryRun(dryRun bool) Option {
+	return func(c *Client) error {
+		c.dryRun = dryRun
+		return nil
+	}
+}
+
+// WithBaseURL directs the Client's requests to base (e.g. a local caching
+// proxy or a test server) rather than https://api.pinboard.in/v1/. Unless the
+// Client is also given WithInsecure, base must be an https URL.
+func WithBaseURL(base string) Option {
+	return func(c *Client) error {
+		u, err := url.Parse(base)
+		if err != nil {
+			return err
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid base URL %q: must be absolute", base)
+		}
+		if !strings.HasSuffix(u.Path, "/") {
+			u.Path += "/"
+		}
+		c.baseURL = u.String()
+		return nil
+	}
+}
+
+// WithProxy sends all requests through the HTTP(S) proxy at proxy, rather
+// than that named in the environment (if any).
+func WithProxy(proxy string) Option {
+	return func(c *Client) error {
+		u, err := url.Parse(proxy)
+		if err != nil {
+			return fmt.Errorf("invalid proxy URL %q: %v", proxy, err)
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid proxy URL %q: must be absolute", proxy)
+		}
+		c.proxy = u
+		return nil
+	}
+}
+
+// WithInsecure permits a base URL that isn't https, and turns off
+// verification of the server's certificate; it's meant only for testing.
+func WithInsecure(insecure bool) Option {
+	return func(c *Client) error {
+		c.insecure = insecure
+		return nil
+	}
+}
+
+// WithMaxResponseSize caps the size of the response body read for any request
+// at n bytes; beyond that, the request fails with ErrResponseTooLarge. By
+// default, the cap is 8MiB, or 512MiB for GetAllPosts & EachPost.
+func WithMaxResponseSize(n int64) Option {
+	return func(c *Client) error {
+		if n <= 0 {
+			return fmt.Errorf("invalid maximum response size %d", n)
+		}
+		c.maxSize = n
+		return nil
+	}
+}
+
+// NewClient returns a Client authenticating with token, which should be of
+// the form "user:HEX".
+func NewClient(token string, opts ...Option) (*Client, error) {
+	c := &Client{
+		token:        token,
+		baseURL:      defaultBaseURL,
+		httpClient:   newHTTPClient(),
+		userAgent:    defaultUserAgent,
+		maxRetries:   defaultMaxRetries,
+		retryCap:     defaultRetryAfterCap,
+		interval:     defaultRateInterval,
+		lastByMethod: make(map[string]time.Time),
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.insecure {
+		log.Warn("Insecure mode: plain HTTP is permitted, and TLS certificates are not verified.")
+	} else if !strings.HasPrefix(c.baseURL, "https://") {
+		return nil, fmt.Errorf("refusing to send the API token to %s, as it doesn't use HTTPS", c.baseURL)
+	}
+
+	// Apply any proxy or TLS settings to a copy of the transport, so as not
+	// to affect an *http.Client supplied by the caller
+	if c.proxy != nil || c.insecure {
+		transport, ok := c.httpClient.Transport.(*http.Transport)
+		if !ok && c.httpClient.Transport != nil {
+			return nil, fmt.Errorf("can't configure a %T", c.httpClient.Transport)
+		}
+		if transport == nil {
+			transport = http.DefaultTransport.(*http.Transport)
+		}
+		transport = transport.Clone()
+		if c.proxy != nil {
+			transport.Proxy = http.ProxyURL(c.proxy)
+		}
+		if c.insecure {
+			if transport.TLSClientConfig == nil {
+				transport.TLSClientConfig = &tls.Config{}
+			}
+			transport.TLSClientConfig.InsecureSkipVerify = true
+		}
+		hc := *c.httpClient
+		hc.Transport = transport
+		c.httpClient = &hc
+	}
+
+	return c, nil
+}
+
+// wait blocks until a request for method may be issued without exceeding
+// Pinboard's rate limits, and records that one is being issued.
+func (c *Client) wait(ctx context.Context, method string) error {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.interval == 0 {
+		return nil
+	}
+
+	next := c.last.Add(c.interval)
+	if d, ok := methodIntervals[method]; ok {
+		if t := c.lastByMethod[method].Add(d); t.After(next) {
+			next = t
+		}
+	}
+
+	if d := time.Until(next); d > 0 {
+		log.Debug(fmt.Sprintf("Waiting %v before calling %s...", d.Round(time.Millisecond), method))
+		if err := sleep(ctx, d); err != nil {
+			return err
+		}
+	}
+
+	c.last = time.Now()
+	if _, ok := methodIntervals[method]; ok {
+		c.lastByMethod[method] = c.last
+	}
+	return nil
+}
+
+// get invokes the API method named by method (e.g. "tags/get"); params should
+// hold only the method-specific arguments, as the token & format are added
+// here. Requests failing with a 429 or 5xx status are retried.
+func (c *Client) get(ctx context.Context, method string, params url.Values) ([]byte, error) {
+
+	var body []byte
+	err := c.getStream(ctx, method, params, func(r io.Reader) error {
+		var err error
+		body, err = io.ReadAll(r)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// getStream is like get, but rather than reading the whole of a successful
+// response's body, hands it to read as it arrives.
+func (c *Client) getStream(ctx context.Context, method string, params url.Values, read func(io.Reader) error) error {
+
+	params.Set("auth_token", c.token)
+	params.Set("format", "json")
+	reqURL := c.baseURL + method + "?" + params.Encode()
+
+	if c.dryRun && mutatingMethods[method] {
+		u, err := url.Parse(reqURL)
+		if err != nil {
+			return err
+		}
+		log.Warn(fmt.Sprintf("Dry run: not sending GET %s", redactURL(u)))
+		return read(strings.NewReader(`{"result_code":"done"}`))
+	}
+
+	for attempt := 0; ; attempt += 1 {
+		rsp, cancel, err := c.send(ctx, method, reqURL)
+		if err != nil {
+			if !c.retryTimeouts || !isTimeout(err) || ctx.Err() != nil || attempt >= c.maxRetries {
+				return fmt.Errorf("%s: %w", method, err)
+			}
+			delay := backoff(attempt, "")
+			if c.retryCap > 0 && delay > c.retryCap {
+				delay = c.retryCap
+			}
+			log.Debug(fmt.Sprintf("%s timed-out; retrying in %v (attempt %d of %d)...",
+				method, delay.Round(time.Millisecond), attempt+1, c.maxRetries))
+			err = sleep(ctx, delay)
+			if err != nil {
+				return fmt.Errorf("%s: %w", method, err)
+			}
+			continue
+		}
+
+		// Pinboard sometimes answers with an HTML error page (even with a
+		// 200), which is treated like a 5xx
+		contentType := rsp.Header.Get("Content-Type")
+		notJSON := false
+		if rsp.StatusCode == http.StatusOK {
+			err = c.readBody(rsp, c.maxResponseSize(method), func(r io.Reader) error {
+				br := bufio.NewReader(r)
+				head, _ := br.Peek(512)
+				if isHTML(contentType, head) {
+					notJSON = true
+					return nil
+				}
+				return read(br)
+			})
+			cancel()
+			if err != nil {
+				return fmt.Errorf("%s: %w", method, err)
+			}
+			if !notJSON {
+				updateValidators(rsp)
+				return nil
+			}
+		}
+		if rsp.StatusCode == http.StatusNotModified {
+			c.readBody(rsp, 0, func(io.Reader) error { return nil })
+			cancel()
+			return fmt.Errorf("%s: %w", method, ErrNotModified)
+		}
+
+		var body []byte
+		if !notJSON {
+			err = c.readBody(rsp, c.maxResponseSize(method), func(r io.Reader) error {
+				body, err = io.ReadAll(r)
+				return err
+			})
+			cancel()
+			if err != nil {
+				return fmt.Errorf("%s: %w", method, err)
+			}
+			notJSON = isHTML(contentType, body)
+		}
+		if !(notJSON || isRetryable(rsp.StatusCode)) || attempt >= c.maxRetries {
+			return &statusError{code: rsp.StatusCode, body: body, notJSON: notJSON}
+		}
+
+		retryAfter := rsp.Header.Get("Retry-After")
+		delay := backoff(attempt, retryAfter)
+		if c.retryCap > 0 && delay > c.retryCap {
+			if retryAfter != "" {
+				return fmt.Errorf("%s: %w", method,
+					&waitError{wait: delay.Round(time.Second), cap: c.retryCap, err: &statusError{code: rsp.StatusCode, body: body, notJSON: notJSON}})
+			}
+			delay = c.retryCap
+		}
+		log.Debug(fmt.Sprintf("%s returned %d; retrying in %v (attempt %d of %d)...",
+			method, rsp.StatusCode, delay.Round(time.Millisecond), attempt+1, c.maxRetries))
+		err = sleep(ctx, delay)
+		if err != nil {
+			return fmt.Errorf("%s: %w", method, err)
+		}
+	}
+}
+
+// isHTML returns true if a response, of type contentType & beginning with
+// head, is an HTML page rather than the JSON requested.
+func isHTML(contentType string, head []byte) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "text/html" {
+		return true
+	}
+	return bytes.HasPrefix(bytes.TrimSpace(head), []byte("<"))
+}
+
+// scrubError masks the auth token in the URL which net/http includes in its
+// errors, so that it's not shown to the user; other errors are returned as-is.
+func scrubError(err error) error {
+	ue, ok := err.(*url.Error)
+	if !ok {
+		return err
+	}
+	scrubbed := *ue
+	u, perr := url.Parse(ue.URL)
+	if perr == nil {
+		scrubbed.URL = redactURL(u)
+	} else {
+		// Can't find the query; drop it all
+		scrubbed.URL = ue.URL[:strings.Index(ue.URL+"?", "?")]
+	}
+	return &scrubbed
+}
+
+// redactURL renders u with the auth token masked, for logging.
+func redactURL(u *url.URL) string {
+	q := u.Query()
+	if q.Get("auth_token") == "" {
+		return u.String()
+	}
+	q.Set("auth_token", "REDACTED")
+	r := *u
+	r.RawQuery = q.Encode()
+	return r.String()
+}
+
+// newRequest builds a GET request for reqURL bearing the headers common to all
+// requests.
+func (c *Client) newRequest(ctx context.Context, reqURL string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, scrubError(err)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	setConditional(req)
+	return req, nil
+}
+
+// send makes a single request for reqURL; the caller must call readBody on
+// the response, and then the returned cancel function.
+func (c *Client) send(ctx context.Context, method, reqURL string) (*http.Response, context.CancelFunc, error) {
+
+	// The time spent waiting on the rate limiter doesn't count against the
+	// timeout, which is for the round trip alone
+	err := c.wait(ctx, method)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cancel := func() {}
+	if c.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+	}
+	req, err := c.newRequest(ctx, reqURL)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	if c.trace != nil {
+		req = req.WithContext(c.traceRequest(ctx, req))
+	}
+
+	logURL := redactURL(req.URL)
+	log.Debug(fmt.Sprintf("GET %s...", logURL))
+	rsp, err := c.httpClient.Do(req)
+	if err != nil {
+		cancel()
+		return nil, nil, scrubError(err)
+	}
+	log.Debug(fmt.Sprintf("GET %s...done(%d).", logURL, rsp.StatusCode))
+
+	return rsp, cancel, nil
+}
+
+func (c *Client) maxResponseSize(method string) int64 {
+	if c.maxSize > 0 {
+		return c.maxSize
+	}
+	if n, ok := methodMaxResponseSizes[method]; ok {
+		return n
+	}
+	return defaultMaxResponseSize
+}
+
+// readBody hands the body of rsp to read (tracing it, if asked), failing
+// if it's longer than max bytes, and closes it.
+func (c *Client) readBody(rsp *http.Response, max int64, read func(io.Reader) error) error {
+
+	defer rsp.Body.Close()
+	var r io.Reader = &cappedReader{r: rsp.Body, max: max}
+	if c.trace == nil {
+		return read(r)
+	}
+
+	c.traceResponse(rsp)
+	tw := c.traceBody()
+	err := read(io.TeeReader(r, tw))
+	tw.flush()
+	fmt.Fprintln(c.trace)
+	return err
+}
+
+// cappedReader reads from r, failing once more than max bytes have been
+// read.
+type cappedReader struct {
+	r    io.Reader
+	max  int64
+	read int64
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+
+	// Read up to one byte more than permitted, so as to notice the excess
+	over := func() error {
+		return fmt.Errorf("%w: the response exceeds %d bytes", ErrResponseTooLarge, c.max)
+	}
+	if c.read > c.max {
+		return 0, over()
+	}
+	if left := c.max - c.read + 1; int64(len(p)) > left {
+		p = p[:left]
+	}
+	n, err := c.r.Read(p)
+	c.read += int64(n)
+	if c.read > c.max {
+		return n - int(c.read-c.max), over()
+	}
+	return n, err
+}
+
+// apiCount is a count in an API response, which Pinboard sometimes renders as
+// a string.
+type apiCount uint64
+
+func (n *apiCount) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	u, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid count %s", b)
+	}
+	*n = apiCount(u)
+	return nil
+}
@@ -0,0 +1,231 @@
+package pinboard
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testToken = "user:0123456789ABCDEF0123"
+
+// newTestClient returns a Client whose requests are answered by handler,
+// with no rate limiting.
+func newTestClient(t *testing.T, handler http.HandlerFunc, opts ...Option) *Client {
+
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	opts = append([]Option{WithBaseURL(srv.URL), WithInsecure(true), WithRateInterval(0)}, opts...)
+	c, err := NewClient(testToken, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// Successive requests should share a connection.
+func TestConnectionReuse(t *testing.T) {
+
+	var mu sync.Mutex
+	conns := 0
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"go": "1"}`))
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			conns += 1
+			mu.Unlock()
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	c, err := NewClient(testToken, WithBaseURL(srv.URL), WithInsecure(true), WithRateInterval(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		_, err = c.GetTags(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if conns != 1 {
+		t.Errorf("three requests opened %d connections; want 1", conns)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(5 * time.Second):
+		case <-r.Context().Done():
+		}
+	}, WithTimeout(50*time.Millisecond))
+
+	start := time.Now()
+	_, err := c.GetTags(context.Background())
+	if err == nil {
+		t.Fatal("GetTags succeeded")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v; want a deadline exceeded", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("GetTags took %v", d)
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+
+	for _, want := range []string{defaultUserAgent, "pin/1.2.3 (+https://github.com/sp1ff/gopin)"} {
+		var got string
+		var opts []Option
+		if want != defaultUserAgent {
+			opts = append(opts, WithUserAgent(want))
+		}
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			got = r.Header.Get("User-Agent")
+			w.Write([]byte(`{}`))
+		}, opts...)
+
+		_, err := c.GetTags(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("got User-Agent %q; want %q", got, want)
+		}
+	}
+}
+
+// In dry-run mode, nothing that would change the user's data is sent.
+func TestDryRun(t *testing.T) {
+
+	var paths []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Write([]byte(`{"go": "1"}`))
+	}, WithDryRun(true))
+
+	ctx := context.Background()
+	for _, err := range []error{
+		c.AddPost(ctx, Post{URL: "https://example.com", Description: "Example"}, false),
+		c.DeletePost(ctx, "https://example.com"),
+		c.RenameTag(ctx, "go", "golang"),
+		c.DeleteTag(ctx, "go"),
+	} {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if len(paths) != 0 {
+		t.Errorf("requests were sent for %v", paths)
+	}
+
+	// ...but reading it is fine
+	_, err := c.GetTags(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 || paths[0] != "/tags/get" {
+		t.Errorf("got requests for %v; want /tags/get", paths)
+	}
+}
+
+func TestProxy(t *testing.T) {
+
+	var host, path string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, path = r.URL.Host, r.URL.Path
+		w.Write([]byte(`{"go": "1"}`))
+	}))
+	defer proxy.Close()
+
+	c, err := NewClient(testToken, WithBaseURL("http://api.pinboard.invalid/v1/"), WithInsecure(true),
+		WithRateInterval(0), WithProxy(proxy.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.GetTags(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host != "api.pinboard.invalid" || path != "/v1/tags/get" {
+		t.Errorf("the proxy got a request for %s%s", host, path)
+	}
+}
+
+func TestMaxResponseSize(t *testing.T) {
+
+	body := `{"go": "1", "emacs": "2", "lisp": "3"}`
+	for _, test := range []struct {
+		max  int64
+		fail bool
+	}{
+		{int64(len(body)), false},
+		{int64(len(body) - 1), true},
+		{16, true},
+	} {
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(body))
+		}, WithMaxResponseSize(test.max))
+
+		_, err := c.GetTags(context.Background())
+		switch {
+		case test.fail && !errors.Is(err, ErrResponseTooLarge):
+			t.Errorf("max %d: got %v; want ErrResponseTooLarge", test.max, err)
+		case !test.fail && err != nil:
+			t.Errorf("max %d: %v", test.max, err)
+		}
+	}
+
+	if _, err := NewClient(testToken, WithMaxResponseSize(0)); err == nil {
+		t.Error("WithMaxResponseSize(0) succeeded")
+	}
+}
+
+// net/http puts the request URL in its errors; the token mustn't appear.
+func TestNetworkErrorRedacted(t *testing.T) {
+
+	// Find a port on which nothing is listening
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	c, err := NewClient(testToken, WithBaseURL("http://"+addr+"/v1/"), WithInsecure(true), WithRateInterval(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.GetTags(context.Background())
+	if err == nil {
+		t.Fatal("GetTags succeeded")
+	}
+	secret := testToken[strings.Index(testToken, ":")+1:]
+	if strings.Contains(err.Error(), secret) {
+		t.Errorf("the token appears in %q", err)
+	}
+	if !strings.Contains(err.Error(), "auth_token=REDACTED") {
+		t.Errorf("%q doesn't show the (redacted) URL", err)
+	}
+	var ue *url.Error
+	if !errors.As(err, &ue) {
+		t.Errorf("%v isn't a *url.Error", err)
+	}
+}
@@ -0,0 +1,57 @@
+package pinboard
+
+import (
+	"context"
+	"net/http"
+)
+
+// Validators identify the version of a response that a caller has cached,
+// so that it may be re-fetched conditionally: if it hasn't changed, Pinboard
+// may answer with 304 Not Modified, & the Client method returns
+// ErrNotModified instead of re-downloading it.
+type Validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+type validatorsKey struct{}
+
+// WithValidators returns a context that makes requests sent with it
+// conditional on v (if it holds anything). v is updated from each successful
+// response, ready to be cached alongside it.
+func WithValidators(ctx context.Context, v *Validators) context.Context {
+	return context.WithValue(ctx, validatorsKey{}, v)
+}
+
+func validatorsFrom(ctx context.Context) *Validators {
+	v, _ := ctx.Value(validatorsKey{}).(*Validators)
+	return v
+}
+
+// setConditional adds the If-None-Match & If-Modified-Since headers to req,
+// per its context's Validators, if any.
+func setConditional(req *http.Request) {
+
+	v := validatorsFrom(req.Context())
+	if v == nil {
+		return
+	}
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+}
+
+// updateValidators records rsp's validators in its request's Validators, if
+// any.
+func updateValidators(rsp *http.Response) {
+
+	v := validatorsFrom(rsp.Request.Context())
+	if v == nil {
+		return
+	}
+	v.ETag = rsp.Header.Get("ETag")
+	v.LastModified = rsp.Header.Get("Last-Modified")
+}
@@ -0,0 +1,58 @@
+package pinboard
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestConditionalRequest(t *testing.T) {
+
+	const etag, lastModified = `"v1"`, "Tue, 02 Jan 2024 03:04:05 GMT"
+	var ifNoneMatch, ifModifiedSince string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		ifNoneMatch, ifModifiedSince = r.Header.Get("If-None-Match"), r.Header.Get("If-Modified-Since")
+		if ifNoneMatch == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		w.Write([]byte(`{"go": "3"}`))
+	})
+
+	// The first request is unconditional, & yields the validators...
+	var v Validators
+	tags, err := c.GetTags(WithValidators(context.Background(), &v))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 1 {
+		t.Errorf("got %v", tags)
+	}
+	if ifNoneMatch != "" || ifModifiedSince != "" {
+		t.Errorf("the first request was conditional (%q, %q)", ifNoneMatch, ifModifiedSince)
+	}
+	if v.ETag != etag || v.LastModified != lastModified {
+		t.Errorf("got validators %+v", v)
+	}
+
+	// ...which make the second conditional
+	_, err = c.GetTags(WithValidators(context.Background(), &v))
+	if !errors.Is(err, ErrNotModified) {
+		t.Errorf("got %v; want ErrNotModified", err)
+	}
+	if ifNoneMatch != etag || ifModifiedSince != lastModified {
+		t.Errorf("got If-None-Match %q, If-Modified-Since %q", ifNoneMatch, ifModifiedSince)
+	}
+
+	// Without validators, requests are unconditional
+	_, err = c.GetTags(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ifNoneMatch != "" || ifModifiedSince != "" {
+		t.Errorf("the request was conditional (%q, %q)", ifNoneMatch, ifModifiedSince)
+	}
+}
@@ -0,0 +1,142 @@
+package pinboard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Errors returned by Client methods wrap these where applicable, so callers
+// may test for them with errors.Is.
+var (
+	// ErrNotFound is returned when the item named in a request doesn't exist
+	// (or the request is answered with a 404).
+	ErrNotFound = errors.New("pinboard: item not found")
+	// ErrExists is returned when adding, without replacing, a bookmark that
+	// already exists.
+	ErrExists = errors.New("pinboard: item already exists")
+	// ErrUnauthorized is returned when Pinboard rejects the API token.
+	ErrUnauthorized = errors.New("pinboard: unauthorized")
+	// ErrRateLimited is returned when Pinboard is still answering 429 Too
+	// Many Requests after all retries.
+	ErrRateLimited = errors.New("pinboard: rate limited")
+	// ErrBadRequest is returned when Pinboard rejects a request as malformed.
+	ErrBadRequest = errors.New("pinboard: bad request")
+	// ErrWaitTooLong is returned when Pinboard asks (via Retry-After) that a
+	// request be retried only after longer than WithRetryAfterCap permits;
+	// such errors also match ErrRateLimited (or whatever the status was).
+	ErrWaitTooLong = errors.New("pinboard: asked to wait too long")
+	// ErrResponseTooLarge is returned when a response is larger than
+	// permitted by WithMaxResponseSize.
+	ErrResponseTooLarge = errors.New("pinboard: response too large")
+	// ErrNotJSON is returned when Pinboard answers with something other
+	// than JSON, usually an HTML error page during an outage.
+	ErrNotJSON = errors.New("pinboard: non-JSON response")
+	// ErrNotModified is returned when a request made conditional with
+	// WithValidators is answered with 304 Not Modified.
+	ErrNotModified = errors.New("pinboard: not modified")
+)
+
+// errorMessage extracts the message from the body of an error response, which
+// Pinboard usually renders as JSON of the form {"result_code": "..."} (or
+// with a "result" or "error" key); if the body isn't in that form, it's
+// returned as-is.
+func errorMessage(body []byte) string {
+	var rsp struct {
+		ResultCode string `json:"result_code"`
+		Result     string `json:"result"`
+		Error      string `json:"error"`
+	}
+	if json.Unmarshal(body, &rsp) == nil {
+		for _, msg := range []string{rsp.ResultCode, rsp.Result, rsp.Error} {
+			if msg != "" {
+				return msg
+			}
+		}
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// statusError is returned for a request answered with a status other than
+// 200, or with an HTML page rather than JSON.
+type statusError struct {
+	code    int
+	body    []byte
+	notJSON bool
+}
+
+func (e *statusError) Is(target error) bool {
+	return target == ErrNotJSON && e.notJSON
+}
+
+func (e *statusError) Unwrap() error {
+	switch e.code {
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusBadRequest:
+		return ErrBadRequest
+	}
+	return nil
+}
+
+func (e *statusError) Error() string {
+	if e.notJSON {
+		return fmt.Sprintf("pinboard returned a non-JSON response (%d %s; service may be down)", e.code, http.StatusText(e.code))
+	}
+	msg := errorMessage(e.body)
+	if msg == "" {
+		return fmt.Sprintf("pinboard: %d %s", e.code, http.StatusText(e.code))
+	}
+	return "pinboard: " + msg
+}
+
+func isStatus(err error, code int) bool {
+	var se *statusError
+	return errors.As(err, &se) && se.code == code
+}
+
+// resultError is returned when a mutating call reports anything other than
+// success.
+type resultError struct {
+	msg string
+}
+
+func (e *resultError) Error() string {
+	return "pinboard: " + e.msg
+}
+
+// checkResult examines the response to a mutating API call, which Pinboard
+// reports as either {"result": "done"} or {"result_code": "done"}; anything
+// other than "done" is an error message.
+func checkResult(body []byte) error {
+	if msg := errorMessage(body); msg != "done" {
+		return &resultError{msg: msg}
+	}
+	return nil
+}
+
+// waitError reports that Pinboard asked for a longer wait than permitted
+// before retrying a request that failed with err.
+type waitError struct {
+	wait, cap time.Duration
+	err       error
+}
+
+func (e *waitError) Error() string {
+	return fmt.Sprintf("Pinboard asked to wait %v before retrying, which is longer than the cap of %v (%v)", e.wait, e.cap, e.err)
+}
+
+func (e *waitError) Unwrap() error {
+	return e.err
+}
+
+func (e *waitError) Is(target error) bool {
+	return target == ErrWaitTooLong
+}
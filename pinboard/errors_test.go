@@ -0,0 +1,128 @@
+package pinboard
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestErrorResponses(t *testing.T) {
+
+	tests := []struct {
+		status int
+		body   string
+		want   string
+	}{
+		{http.StatusUnauthorized, `{"error": "invalid API token"}`, "pinboard: invalid API token"},
+		{http.StatusTooManyRequests, `{"result_code": "too many requests"}`, "pinboard: too many requests"},
+		{http.StatusUnauthorized, "", "pinboard: 401 Unauthorized"},
+		{http.StatusTooManyRequests, "slow down\n", "pinboard: slow down"},
+	}
+	for _, test := range tests {
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(test.status)
+			w.Write([]byte(test.body))
+		}, WithMaxRetries(0))
+
+		_, err := c.GetTags(context.Background())
+		if err == nil {
+			t.Errorf("%d %q: no error", test.status, test.body)
+			continue
+		}
+		if err.Error() != test.want {
+			t.Errorf("%d %q: got %q; want %q", test.status, test.body, err.Error(), test.want)
+		}
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+
+	sentinels := []error{ErrUnauthorized, ErrRateLimited, ErrNotFound, ErrBadRequest}
+	tests := []struct {
+		status int
+		want   error // nil for none
+	}{
+		{http.StatusUnauthorized, ErrUnauthorized},
+		{http.StatusTooManyRequests, ErrRateLimited},
+		{http.StatusNotFound, ErrNotFound},
+		{http.StatusBadRequest, ErrBadRequest},
+		{http.StatusInternalServerError, nil},
+		{http.StatusForbidden, nil},
+	}
+	for _, test := range tests {
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(test.status)
+		}, WithMaxRetries(0))
+
+		_, err := c.GetTags(context.Background())
+		if err == nil {
+			t.Errorf("%d: no error", test.status)
+			continue
+		}
+		for _, sentinel := range sentinels {
+			if got := errors.Is(err, sentinel); got != (sentinel == test.want) {
+				t.Errorf("%d: errors.Is(%v, %v) = %v", test.status, err, sentinel, got)
+			}
+		}
+	}
+}
+
+func TestAddPostExists(t *testing.T) {
+
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result_code": "item already exists"}`))
+	})
+	err := c.AddPost(context.Background(), Post{URL: "https://example.com", Description: "Example"}, false)
+	if !errors.Is(err, ErrExists) {
+		t.Errorf("got %v; want ErrExists", err)
+	}
+}
+
+func TestGetPostNotFound(t *testing.T) {
+
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"date": "", "user": "user", "posts": []}`))
+	})
+	_, err := c.GetPost(context.Background(), "https://example.com")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v; want ErrNotFound", err)
+	}
+}
+
+// Pinboard sometimes answers with an HTML error page, even with a 200.
+func TestHTMLResponse(t *testing.T) {
+
+	page := "<!DOCTYPE html>\n<html><head><title>Pinboard is down</title></head><body>Try later.</body></html>"
+	for _, test := range []struct {
+		status      int
+		contentType string
+	}{
+		{http.StatusServiceUnavailable, "text/html; charset=utf-8"},
+		{http.StatusOK, "text/html"},
+		// mislabelled
+		{http.StatusOK, "application/json"},
+	} {
+		requests := 0
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			requests += 1
+			w.Header().Set("Content-Type", test.contentType)
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(test.status)
+			w.Write([]byte(page))
+		}, WithMaxRetries(1))
+
+		_, err := c.GetTags(context.Background())
+		if !errors.Is(err, ErrNotJSON) {
+			t.Errorf("%d %s: got %v; want ErrNotJSON", test.status, test.contentType, err)
+		}
+		if err != nil && strings.Contains(err.Error(), "<html>") {
+			t.Errorf("%d %s: the page is in the message %q", test.status, test.contentType, err)
+		}
+		// such responses are retried, like a 5xx
+		if requests != 2 {
+			t.Errorf("%d %s: got %d requests; want 2", test.status, test.contentType, requests)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package pinboard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Note is one of the user's notes; Text is only populated by GetNote.
+type Note struct {
+	ID      string    `json:"id"`
+	Title   string    `json:"title"`
+	Length  uint64    `json:"length"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+	Text    string    `json:"text,omitempty"`
+}
+
+// apiNote is a Note as represented on the wire.
+type apiNote struct {
+	ID        string   `json:"id"`
+	Title     string   `json:"title"`
+	Length    apiCount `json:"length"`
+	CreatedAt string   `json:"created_at"`
+	UpdatedAt string   `json:"updated_at"`
+	Text      string   `json:"text"`
+}
+
+// Layout of note timestamps (which are in UTC)
+const noteTimeLayout = "2006-01-02 15:04:05"
+
+func (n apiNote) note() (Note, error) {
+	created, err := time.Parse(noteTimeLayout, n.CreatedAt)
+	if err != nil {
+		return Note{}, fmt.Errorf("bad creation time for note %s: %w", n.ID, err)
+	}
+	updated, err := time.Parse(noteTimeLayout, n.UpdatedAt)
+	if err != nil {
+		return Note{}, fmt.Errorf("bad update time for note %s: %w", n.ID, err)
+	}
+	return Note{
+		ID:      n.ID,
+		Title:   n.Title,
+		Length:  uint64(n.Length),
+		Created: created,
+		Updated: updated,
+		Text:    n.Text,
+	}, nil
+}
+
+// ListNotes retrieves all the user's notes, without their text.
+func (c *Client) ListNotes(ctx context.Context) ([]Note, error) {
+
+	body, err := c.get(ctx, "notes/list", url.Values{})
+	if err != nil {
+		return nil, err
+	}
+
+	var rsp struct {
+		Notes []apiNote `json:"notes"`
+	}
+	err = json.Unmarshal(body, &rsp)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Note, 0, len(rsp.Notes))
+	for _, n := range rsp.Notes {
+		note, err := n.note()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, note)
+	}
+	return result, nil
+}
+
+// GetNote retrieves the note with the given id, text included, returning
+// ErrNotFound if there is none.
+func (c *Client) GetNote(ctx context.Context, id string) (Note, error) {
+
+	body, err := c.get(ctx, "notes/"+url.PathEscape(id), url.Values{})
+	if isStatus(err, http.StatusNotFound) {
+		return Note{}, ErrNotFound
+	}
+	if err != nil {
+		return Note{}, err
+	}
+
+	var n apiNote
+	err = json.Unmarshal(body, &n)
+	if err != nil {
+		return Note{}, err
+	}
+	if n.ID == "" {
+		return Note{}, ErrNotFound
+	}
+
+	return n.note()
+}
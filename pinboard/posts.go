@@ -0,0 +1,330 @@
+package pinboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Post is a single bookmark.
+type Post struct {
+	URL         string    `json:"url"`
+	Description string    `json:"description"`
+	Extended    string    `json:"extended"`
+	Tags        []string  `json:"tags"`
+	Time        time.Time `json:"time"`
+	Shared      bool      `json:"shared"`
+	ToRead      bool      `json:"toread"`
+}
+
+// apiPost is a Post as represented on the wire.
+type apiPost struct {
+	Href        string `json:"href"`
+	Description string `json:"description"`
+	Extended    string `json:"extended"`
+	Tags        string `json:"tags"`
+	Time        string `json:"time"`
+	Shared      string `json:"shared"`
+	ToRead      string `json:"toread"`
+}
+
+func (p apiPost) post() (Post, error) {
+	t, err := time.Parse(time.RFC3339, p.Time)
+	if err != nil {
+		return Post{}, fmt.Errorf("bad timestamp for %s: %w", p.Href, err)
+	}
+	return Post{
+		URL:         p.Href,
+		Description: p.Description,
+		Extended:    p.Extended,
+		Tags:        strings.Fields(p.Tags),
+		Time:        t,
+		Shared:      p.Shared == "yes",
+		ToRead:      p.ToRead == "yes",
+	}, nil
+}
+
+// MaxFilterTags is the greatest number of tags by which Pinboard will filter
+// posts.
+const MaxFilterTags = 3
+
+// AllPostsOptions narrows the bookmarks returned by GetAllPosts; the zero
+// value selects all of them.
+type AllPostsOptions struct {
+	// Return only bookmarks bearing all these tags (at most MaxFilterTags)
+	Tags []string
+	// Return at most this many bookmarks, if non-zero
+	Results int
+	// Return only bookmarks made after From, if non-zero
+	From time.Time
+	// Return only bookmarks made before To, if non-zero
+	To time.Time
+}
+
+// GetAllPosts retrieves all the user's bookmarks, most recent first. Pinboard
+// permits this call only once every five minutes.
+func (c *Client) GetAllPosts(ctx context.Context, opts AllPostsOptions) ([]Post, error) {
+
+	var posts []Post
+	err := c.EachPost(ctx, opts, func(post Post) error {
+		posts = append(posts, post)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// EachPost is like GetAllPosts, but rather than collecting the bookmarks,
+// calls fn with each as it's read from the response, so that they needn't
+// all be held in memory at once. If fn returns an error, EachPost stops &
+// returns it (wrapped).
+func (c *Client) EachPost(ctx context.Context, opts AllPostsOptions, fn func(Post) error) error {
+
+	if len(opts.Tags) > MaxFilterTags {
+		return fmt.Errorf("at most %d tags may be given", MaxFilterTags)
+	}
+
+	params := url.Values{}
+	if len(opts.Tags) != 0 {
+		params.Set("tag", strings.Join(opts.Tags, " "))
+	}
+	if opts.Results > 0 {
+		params.Set("results", strconv.Itoa(opts.Results))
+	}
+	if !opts.From.IsZero() {
+		params.Set("fromdt", opts.From.UTC().Format(time.RFC3339))
+	}
+	if !opts.To.IsZero() {
+		params.Set("todt", opts.To.UTC().Format(time.RFC3339))
+	}
+	return c.getStream(ctx, "posts/all", params, func(r io.Reader) error {
+		dec := json.NewDecoder(r)
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+			return fmt.Errorf("expected an array of bookmarks, got %v", tok)
+		}
+		for dec.More() {
+			var p apiPost
+			err = dec.Decode(&p)
+			if err != nil {
+				return err
+			}
+			post, err := p.post()
+			if err != nil {
+				return err
+			}
+			err = fn(post)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+		return err
+	})
+}
+
+// LastUpdate retrieves the time at which the user's bookmarks last changed;
+// it's far cheaper than GetAllPosts, & so is the recommended way to decide
+// whether the latter needs to be called.
+func (c *Client) LastUpdate(ctx context.Context) (time.Time, error) {
+
+	body, err := c.get(ctx, "posts/update", url.Values{})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var rsp struct {
+		UpdateTime string `json:"update_time"`
+	}
+	err = json.Unmarshal(body, &rsp)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Parse(time.RFC3339, rsp.UpdateTime)
+}
+
+// MaxRecentPosts is the greatest number of bookmarks GetRecentPosts will
+// return.
+const MaxRecentPosts = 100
+
+// GetRecentPosts retrieves the user's count most recent bookmarks (at most
+// MaxRecentPosts; zero means Pinboard's default of fifteen), optionally
+// restricted to those bearing all of tags. Pinboard permits this call only
+// once a minute.
+func (c *Client) GetRecentPosts(ctx context.Context, tags []string, count int) ([]Post, error) {
+
+	if len(tags) > MaxFilterTags {
+		return nil, fmt.Errorf("at most %d tags may be given", MaxFilterTags)
+	}
+	if count < 0 || count > MaxRecentPosts {
+		return nil, fmt.Errorf("count must be between 0 and %d (0 for the default)", MaxRecentPosts)
+	}
+
+	params := url.Values{}
+	if len(tags) != 0 {
+		params.Set("tag", strings.Join(tags, " "))
+	}
+	if count > 0 {
+		params.Set("count", strconv.Itoa(count))
+	}
+	body, err := c.get(ctx, "posts/recent", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var rsp struct {
+		Posts []apiPost `json:"posts"`
+	}
+	err = json.Unmarshal(body, &rsp)
+	if err != nil {
+		return nil, err
+	}
+
+	return toPosts(rsp.Posts)
+}
+
+// GetPost retrieves the bookmark for u, returning ErrNotFound if there is
+// none.
+func (c *Client) GetPost(ctx context.Context, u string) (Post, error) {
+
+	params := url.Values{}
+	params.Set("url", u)
+	body, err := c.get(ctx, "posts/get", params)
+	if err != nil {
+		return Post{}, err
+	}
+
+	var rsp struct {
+		Posts []apiPost `json:"posts"`
+	}
+	err = json.Unmarshal(body, &rsp)
+	if err != nil {
+		return Post{}, err
+	}
+	if len(rsp.Posts) == 0 {
+		return Post{}, ErrNotFound
+	}
+
+	return rsp.Posts[0].post()
+}
+
+// DateCount is the number of bookmarks created on a given day.
+type DateCount struct {
+	// In the form YYYY-MM-DD
+	Date  string `json:"date"`
+	Count uint64 `json:"count"`
+}
+
+// GetPostDates retrieves the number of bookmarks the user created on each day,
+// in chronological order, optionally restricted to those bearing tag.
+func (c *Client) GetPostDates(ctx context.Context, tag string) ([]DateCount, error) {
+
+	params := url.Values{}
+	if tag != "" {
+		params.Set("tag", tag)
+	}
+	body, err := c.get(ctx, "posts/dates", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var rsp struct {
+		Dates map[string]apiCount `json:"dates"`
+	}
+	err = json.Unmarshal(body, &rsp)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]DateCount, 0, len(rsp.Dates))
+	for d, n := range rsp.Dates {
+		result = append(result, DateCount{Date: d, Count: uint64(n)})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Date < result[j].Date })
+	return result, nil
+}
+
+func toPosts(posts []apiPost) ([]Post, error) {
+	result := make([]Post, 0, len(posts))
+	for _, p := range posts {
+		post, err := p.post()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, post)
+	}
+	return result, nil
+}
+
+// YesNo renders b as Pinboard does: "yes" or "no".
+func YesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// AddPost bookmarks post.URL; if replace is false & the URL has already been
+// bookmarked, ErrExists is returned. If post.Time is zero, the bookmark is
+// timestamped with the current time.
+func (c *Client) AddPost(ctx context.Context, post Post, replace bool) error {
+
+	params := url.Values{}
+	params.Set("url", post.URL)
+	params.Set("description", post.Description)
+	if post.Extended != "" {
+		params.Set("extended", post.Extended)
+	}
+	if len(post.Tags) != 0 {
+		params.Set("tags", strings.Join(post.Tags, " "))
+	}
+	if !post.Time.IsZero() {
+		params.Set("dt", post.Time.UTC().Format(time.RFC3339))
+	}
+	params.Set("replace", YesNo(replace))
+	params.Set("shared", YesNo(post.Shared))
+	params.Set("toread", YesNo(post.ToRead))
+	body, err := c.get(ctx, "posts/add", params)
+	if err != nil {
+		return err
+	}
+
+	err = checkResult(body)
+	var re *resultError
+	if errors.As(err, &re) && re.msg == "item already exists" {
+		return ErrExists
+	}
+	return err
+}
+
+// DeletePost removes the bookmark for u, returning ErrNotFound if there is
+// none.
+func (c *Client) DeletePost(ctx context.Context, u string) error {
+
+	params := url.Values{}
+	params.Set("url", u)
+	body, err := c.get(ctx, "posts/delete", params)
+	if err != nil {
+		return err
+	}
+
+	err = checkResult(body)
+	var re *resultError
+	if errors.As(err, &re) && re.msg == "item not found" {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,130 @@
+package pinboard
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"runtime"
+	"testing"
+)
+
+// writePosts streams a posts/all response of n synthetic bookmarks to w,
+// returning its size in bytes.
+func writePosts(w http.ResponseWriter, n int) int {
+
+	bw := bufio.NewWriter(w)
+	size, _ := bw.WriteString("[")
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			bw.WriteString(",")
+			size += 1
+		}
+		m, _ := fmt.Fprintf(bw, `{"href":"https://example.com/%d","description":"Bookmark number %d","extended":"%0200d","meta":"","hash":"","time":"2024-01-02T03:04:05Z","shared":"yes","toread":"no","tags":"synthetic test"}`, i, i, i)
+		size += m
+	}
+	m, _ := bw.WriteString("]")
+	bw.Flush()
+	return size + m
+}
+
+// heapInUse returns the bytes allocated on the heap & still in use, after a
+// collection.
+func heapInUse() uint64 {
+	var ms runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&ms)
+	return ms.HeapAlloc
+}
+
+// EachPost should need memory for only a bookmark or so at a time, however
+// long the response. With 100,000 bookmarks (some 39MB of JSON), the heap
+// grows by about a megabyte while streaming them, where GetAllPosts holds
+// the lot (over 40MB).
+func TestEachPostStreams(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("skipping the large fixture in short mode")
+	}
+
+	const n = 100000
+	size := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		size = writePosts(w, n)
+	})
+
+	base := heapInUse()
+	var peak uint64
+	count := 0
+	err := c.EachPost(context.Background(), AllPostsOptions{}, func(post Post) error {
+		count += 1
+		if count%10000 == 0 {
+			if used := heapInUse(); used > peak {
+				peak = used
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != n {
+		t.Fatalf("got %d bookmarks; want %d", count, n)
+	}
+	growth := int64(peak) - int64(base)
+	t.Logf("streamed %d bytes of bookmarks; the heap grew by %d bytes", size, growth)
+	if growth > int64(size/10) {
+		t.Errorf("the heap grew by %d bytes while streaming %d", growth, size)
+	}
+
+	base = heapInUse()
+	posts, err := c.GetAllPosts(context.Background(), AllPostsOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("collecting them all grew the heap by %d bytes", int64(heapInUse())-int64(base))
+	runtime.KeepAlive(posts)
+}
+
+func TestGetRecentPostsCount(t *testing.T) {
+
+	var query url.Values
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Write([]byte(`{"date": "2024-01-02T03:04:05Z", "user": "user", "posts": []}`))
+	})
+
+	tests := []struct {
+		count int
+		// the count sent, if any
+		sent string
+		err  string
+	}{
+		{-1, "", "count must be between 0 and 100 (0 for the default)"},
+		{0, "", ""},
+		{1, "1", ""},
+		{MaxRecentPosts, "100", ""},
+		{MaxRecentPosts + 1, "", "count must be between 0 and 100 (0 for the default)"},
+	}
+	for _, test := range tests {
+		query = nil
+		_, err := c.GetRecentPosts(context.Background(), nil, test.count)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%d: got %v; want %q", test.count, err, test.err)
+			}
+			if query != nil {
+				t.Errorf("%d: the request was sent", test.count)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d: %v", test.count, err)
+			continue
+		}
+		if got := query.Get("count"); got != test.sent || query.Has("count") != (test.sent != "") {
+			t.Errorf("%d: sent count %q; want %q", test.count, got, test.sent)
+		}
+	}
+}
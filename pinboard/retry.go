@@ -0,0 +1,73 @@
+package pinboard
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultMaxRetries    = 3
+	maxMaxRetries        = 10
+	baseRetryDelay       = time.Second
+	maxRetryDelay        = 10 * time.Minute
+	defaultRetryAfterCap = time.Minute
+	// Past this many failures, the delay stops growing (and a longer shift
+	// would overflow)
+	maxBackoffAttempt = 20
+)
+
+func isRetryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// isTimeout returns true if err reports that a request got no response in
+// time.
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &ne) && ne.Timeout())
+}
+
+// backoff returns the time to wait before retrying after the attempt'th
+// failure (counting from zero). A Retry-After header, if present & valid, is
+// honored; else the delay grows exponentially, with jitter, up to
+// maxRetryDelay.
+func backoff(attempt int, retryAfter string) time.Duration {
+
+	if retryAfter != "" {
+		if secs, err := strconv.Atoi(retryAfter); err == nil && secs >= 0 {
+			return time.Duration(secs) * time.Second
+		}
+		if t, err := http.ParseTime(retryAfter); err == nil {
+			if d := time.Until(t); d > 0 {
+				return d
+			}
+			return 0
+		}
+	}
+
+	if attempt > maxBackoffAttempt {
+		attempt = maxBackoffAttempt
+	}
+	d := baseRetryDelay << uint(attempt)
+	if d > maxRetryDelay {
+		d = maxRetryDelay
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// sleep pauses for d, or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,188 @@
+package pinboard
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+
+	for attempt := 0; attempt < 64; attempt++ {
+		d := backoff(attempt, "")
+		if d <= 0 || d > maxRetryDelay {
+			t.Errorf("backoff(%d) = %v; want (0, %v]", attempt, d, maxRetryDelay)
+		}
+	}
+
+	if d := backoff(0, "7"); d != 7*time.Second {
+		t.Errorf("backoff(0, \"7\") = %v; want 7s", d)
+	}
+	past := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+	if d := backoff(0, past); d != 0 {
+		t.Errorf("backoff(0, %q) = %v; want 0", past, d)
+	}
+}
+
+func TestWithMaxRetries(t *testing.T) {
+
+	for _, n := range []int{-1, maxMaxRetries + 1} {
+		if _, err := NewClient(testToken, WithMaxRetries(n)); err == nil {
+			t.Errorf("WithMaxRetries(%d) succeeded", n)
+		}
+	}
+	if _, err := NewClient(testToken, WithMaxRetries(maxMaxRetries)); err != nil {
+		t.Errorf("WithMaxRetries(%d): %v", maxMaxRetries, err)
+	}
+}
+
+// A server that fails twice with 429 before succeeding should see the
+// request succeed, after two retries.
+func TestRetryFlakyServer(t *testing.T) {
+
+	requests := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests += 1
+		if requests <= 2 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"go": "3"}`))
+	})
+
+	tags, err := c.GetTags(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 3 {
+		t.Errorf("got %d requests; want 3", requests)
+	}
+	if len(tags) != 1 || tags[0].Name != "go" || tags[0].UseCount != 3 {
+		t.Errorf("got %v", tags)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+
+	requests := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests += 1
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, WithMaxRetries(2))
+
+	_, err := c.GetTags(context.Background())
+	if err == nil {
+		t.Fatal("GetTags succeeded")
+	}
+	if requests != 3 {
+		t.Errorf("got %d requests; want 3", requests)
+	}
+}
+
+// If Pinboard asks for a longer wait than the cap permits, give up at once.
+func TestRetryAfterCap(t *testing.T) {
+
+	requests := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests += 1
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}, WithRetryAfterCap(time.Minute))
+
+	start := time.Now()
+	_, err := c.GetTags(context.Background())
+	if !errors.Is(err, ErrWaitTooLong) || !errors.Is(err, ErrRateLimited) {
+		t.Errorf("got %v; want ErrWaitTooLong & ErrRateLimited", err)
+	}
+	if requests != 1 {
+		t.Errorf("got %d requests; want 1", requests)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("GetTags took %v", d)
+	}
+}
+
+// A computed backoff longer than the cap is shortened to it.
+func TestRetryAfterCapShortensBackoff(t *testing.T) {
+
+	requests := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests += 1
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}, WithRetryAfterCap(10*time.Millisecond))
+
+	start := time.Now()
+	_, err := c.GetTags(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 400*time.Millisecond {
+		t.Errorf("GetTags took %v; the backoff should have been capped at 10ms", d)
+	}
+}
+
+// A server that hangs on the first request, but answers the second.
+func hangOnce(requests *int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(requests, 1) == 1 {
+			select {
+			case <-time.After(5 * time.Second):
+			case <-r.Context().Done():
+			}
+			return
+		}
+		w.Write([]byte(`{"go": "3"}`))
+	}
+}
+
+func TestRetryOnTimeout(t *testing.T) {
+
+	var requests int32
+	c := newTestClient(t, hangOnce(&requests), WithTimeout(50*time.Millisecond),
+		WithRetryOnTimeout(true), WithRetryAfterCap(10*time.Millisecond))
+
+	tags, err := c.GetTags(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("got %d requests; want 2", n)
+	}
+	if len(tags) != 1 || tags[0].Name != "go" {
+		t.Errorf("got %v", tags)
+	}
+}
+
+// Timeouts aren't retried by default, nor beyond the retry budget.
+func TestNoRetryOnTimeout(t *testing.T) {
+
+	var requests int32
+	c := newTestClient(t, hangOnce(&requests), WithTimeout(50*time.Millisecond))
+	_, err := c.GetTags(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v; want a deadline exceeded", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("got %d requests; want 1", n)
+	}
+
+	requests = 0
+	c = newTestClient(t, hangOnce(&requests), WithTimeout(50*time.Millisecond),
+		WithRetryOnTimeout(true), WithMaxRetries(0))
+	_, err = c.GetTags(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v; want a deadline exceeded", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("got %d requests; want 1", n)
+	}
+}
@@ -0,0 +1,41 @@
+package pinboard
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+)
+
+// Suggestions are the tags Pinboard proposes for a URL: "popular" tags are
+// those other users have applied to it, "recommended" those drawn from the
+// user's own tags.
+type Suggestions struct {
+	Popular     []string `json:"popular"`
+	Recommended []string `json:"recommended"`
+}
+
+// SuggestTags retrieves tag suggestions for u.
+func (c *Client) SuggestTags(ctx context.Context, u string) (Suggestions, error) {
+
+	params := url.Values{}
+	params.Set("url", u)
+	body, err := c.get(ctx, "posts/suggest", params)
+	if err != nil {
+		return Suggestions{}, err
+	}
+
+	// The response is an array of single-key objects, one per kind of
+	// suggestion.
+	var rsp []Suggestions
+	err = json.Unmarshal(body, &rsp)
+	if err != nil {
+		return Suggestions{}, err
+	}
+
+	result := Suggestions{Popular: []string{}, Recommended: []string{}}
+	for _, s := range rsp {
+		result.Popular = append(result.Popular, s.Popular...)
+		result.Recommended = append(result.Recommended, s.Recommended...)
+	}
+	return result, nil
+}
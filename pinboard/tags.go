@@ -0,0 +1,99 @@
+package pinboard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Tag is one of the user's tags, along with the number of bookmarks bearing it.
+type Tag struct {
+	Name     string `json:"name"`
+	UseCount uint64 `json:"use_count"`
+}
+
+// ValidateTag checks that name may be used as a tag: Pinboard tags may not be
+// empty, nor contain commas or whitespace.
+func ValidateTag(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("invalid tag %q: tags may not be empty", name)
+	case strings.ContainsRune(name, ','):
+		return fmt.Errorf("invalid tag %q: tags may not contain commas", name)
+	case strings.IndexFunc(name, unicode.IsSpace) >= 0:
+		return fmt.Errorf("invalid tag %q: tags may not contain whitespace", name)
+	}
+	return nil
+}
+
+// GetTags retrieves all the user's tags, in no particular order.
+func (c *Client) GetTags(ctx context.Context) ([]Tag, error) {
+
+	body, err := c.get(ctx, "tags/get", url.Values{})
+	if err != nil {
+		return nil, err
+	}
+	return ParseTags(body)
+}
+
+// ParseTags parses the body of a response to tags/get (as saved from an
+// earlier call, say), a JSON object mapping each tag to its use count.
+func ParseTags(body []byte) ([]Tag, error) {
+
+	var tags map[string]string
+	err := json.Unmarshal(body, &tags)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Tag, 0, len(tags))
+	for k, v := range tags {
+		uc, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, Tag{Name: k, UseCount: uc})
+	}
+
+	return result, nil
+}
+
+// RenameTag renames the tag old to new; if new already exists, old is folded
+// into it.
+func (c *Client) RenameTag(ctx context.Context, old, new string) error {
+
+	for _, tag := range []string{old, new} {
+		if err := ValidateTag(tag); err != nil {
+			return err
+		}
+	}
+	params := url.Values{}
+	params.Set("old", old)
+	params.Set("new", new)
+	body, err := c.get(ctx, "tags/rename", params)
+	if err != nil {
+		return err
+	}
+
+	return checkResult(body)
+}
+
+// DeleteTag removes tag from all the user's bookmarks.
+func (c *Client) DeleteTag(ctx context.Context, tag string) error {
+
+	if err := ValidateTag(tag); err != nil {
+		return err
+	}
+	params := url.Values{}
+	params.Set("tag", tag)
+	body, err := c.get(ctx, "tags/delete", params)
+	if err != nil {
+		return err
+	}
+
+	return checkResult(body)
+}
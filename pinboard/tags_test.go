@@ -0,0 +1,91 @@
+package pinboard
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRenameTagResult(t *testing.T) {
+
+	tests := []struct {
+		body string
+		want string // error message; empty for none
+	}{
+		{`{"result":"done"}`, ""},
+		{`{"result_code":"done"}`, ""},
+		{`{"result":"rename to tag failed"}`, "pinboard: rename to tag failed"},
+	}
+	for _, test := range tests {
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(test.body))
+		})
+		err := c.RenameTag(context.Background(), "go", "golang")
+		switch {
+		case test.want == "" && err != nil:
+			t.Errorf("%s: %v", test.body, err)
+		case test.want != "" && (err == nil || err.Error() != test.want):
+			t.Errorf("%s: got %v; want %q", test.body, err, test.want)
+		}
+	}
+}
+
+// Tags may hold characters with special meaning in a query string.
+func TestRenameTagEscaping(t *testing.T) {
+
+	var query url.Values
+	var rawQuery string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		query, rawQuery = r.URL.Query(), r.URL.RawQuery
+		w.Write([]byte(`{"result":"done"}`))
+	})
+
+	err := c.RenameTag(context.Background(), "c++&friends", "c#/c++")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query.Get("old") != "c++&friends" || query.Get("new") != "c#/c++" {
+		t.Errorf("got old=%q, new=%q", query.Get("old"), query.Get("new"))
+	}
+	for _, want := range []string{"old=c%2B%2B%26friends", "new=c%23%2Fc%2B%2B"} {
+		if !strings.Contains(rawQuery, want) {
+			t.Errorf("%q doesn't contain %q", rawQuery, want)
+		}
+	}
+}
+
+func TestValidateTag(t *testing.T) {
+
+	for _, name := range []string{"go", "c++", "日本語", ".hidden", "a/b", "über"} {
+		if err := ValidateTag(name); err != nil {
+			t.Errorf("ValidateTag(%q): %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "a,b", "two words", "tab\there", "new\nline", "nbsp "} {
+		if err := ValidateTag(name); err == nil {
+			t.Errorf("ValidateTag(%q) succeeded", name)
+		}
+	}
+}
+
+// Invalid tags are rejected before anything is sent.
+func TestInvalidTagNotSent(t *testing.T) {
+
+	requests := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests += 1
+		w.Write([]byte(`{"result":"done"}`))
+	})
+	ctx := context.Background()
+	if err := c.RenameTag(ctx, "go", "go lang"); err == nil {
+		t.Error("RenameTag succeeded")
+	}
+	if err := c.DeleteTag(ctx, "a,b"); err == nil {
+		t.Error("DeleteTag succeeded")
+	}
+	if requests != 0 {
+		t.Errorf("%d requests were sent", requests)
+	}
+}
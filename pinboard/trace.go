@@ -0,0 +1,135 @@
+package pinboard
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WithTrace makes the Client write a transcript of each request to w: the
+// request line & headers, the timing of the connection, and the response
+// status, headers & body. The auth token (wherever it appears), & any
+// credentials in the headers, are redacted.
+func WithTrace(w io.Writer) Option {
+	return func(c *Client) error {
+		c.trace = w
+		return nil
+	}
+}
+
+// Headers whose values are never traced
+var secretHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+func traceHeaders(w io.Writer, prefix string, h http.Header) {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range h[name] {
+			if secretHeaders[http.CanonicalHeaderKey(name)] {
+				v = "REDACTED"
+			}
+			fmt.Fprintf(w, "%s %s: %s\n", prefix, name, v)
+		}
+	}
+}
+
+// traceRequest writes req to c.trace & returns a context that will trace the
+// progress of its connection.
+func (c *Client) traceRequest(ctx context.Context, req *http.Request) context.Context {
+
+	u := *req.URL
+	path := strings.TrimPrefix(redactURL(&u), u.Scheme+"://"+u.Host)
+	fmt.Fprintf(c.trace, "> %s %s %s\n", req.Method, path, req.Proto)
+	fmt.Fprintf(c.trace, "> Host: %s\n", req.URL.Host)
+	traceHeaders(c.trace, ">", req.Header)
+
+	start := time.Now()
+	since := func() time.Duration { return time.Since(start).Round(time.Microsecond) }
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			fmt.Fprintf(c.trace, "* Connected to %s (reused: %t) after %v\n", info.Conn.RemoteAddr(), info.Reused, since())
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			fmt.Fprintf(c.trace, "* DNS lookup done after %v\n", since())
+		},
+		ConnectDone: func(network, addr string, err error) {
+			if err != nil {
+				fmt.Fprintf(c.trace, "* Failed to connect to %s after %v: %v\n", addr, since(), err)
+			}
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			if err != nil {
+				fmt.Fprintf(c.trace, "* TLS handshake failed after %v: %v\n", since(), err)
+				return
+			}
+			fmt.Fprintf(c.trace, "* TLS handshake (%s) done after %v\n", tls.VersionName(state.Version), since())
+		},
+		GotFirstResponseByte: func() {
+			fmt.Fprintf(c.trace, "* First response byte after %v\n", since())
+		},
+	})
+}
+
+// traceResponse writes the status & headers of rsp to c.trace; its body is
+// traced as it's read.
+func (c *Client) traceResponse(rsp *http.Response) {
+	fmt.Fprintf(c.trace, "< %s %s\n", rsp.Proto, rsp.Status)
+	traceHeaders(c.trace, "<", rsp.Header)
+	fmt.Fprintln(c.trace, "<")
+}
+
+// traceBody returns a writer that traces a response body, with the secret
+// part of the auth token (as in the response to user/api_token) redacted.
+func (c *Client) traceBody() *redactWriter {
+	secret := c.token[strings.LastIndex(c.token, ":")+1:]
+	return &redactWriter{w: c.trace, secret: []byte(secret)}
+}
+
+// redactWriter writes to w with every occurrence of secret replaced. As an
+// occurrence may be split across writes, the last few bytes of each are held
+// back until the next, or until flush is called.
+type redactWriter struct {
+	w       io.Writer
+	secret  []byte
+	pending []byte
+}
+
+func (rw *redactWriter) Write(p []byte) (int, error) {
+
+	if len(rw.secret) == 0 {
+		return rw.w.Write(p)
+	}
+	buf := bytes.ReplaceAll(append(rw.pending, p...), rw.secret, []byte("REDACTED"))
+	keep := len(rw.secret) - 1
+	if keep > len(buf) {
+		keep = len(buf)
+	}
+	rw.pending = append(rw.pending[:0], buf[len(buf)-keep:]...)
+	_, err := rw.w.Write(buf[:len(buf)-keep])
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// flush writes whatever's been held back.
+func (rw *redactWriter) flush() error {
+	_, err := rw.w.Write(rw.pending)
+	rw.pending = rw.pending[:0]
+	return err
+}
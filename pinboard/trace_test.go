@@ -0,0 +1,50 @@
+package pinboard
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// The token's secret is redacted from the trace, wherever it appears.
+func TestTraceRedacted(t *testing.T) {
+
+	secret := testToken[strings.Index(testToken, ":")+1:]
+	var trace bytes.Buffer
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result": "` + secret + `"}`))
+	}, WithTrace(&trace))
+
+	_, err := c.Secret(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(trace.String(), secret) {
+		t.Errorf("the token appears in the trace:\n%s", trace.String())
+	}
+	if !strings.Contains(trace.String(), "auth_token=REDACTED") || !strings.Contains(trace.String(), `{"result": "REDACTED"}`) {
+		t.Errorf("got\n%s", trace.String())
+	}
+}
+
+// The secret is caught even when split across writes.
+func TestRedactWriter(t *testing.T) {
+
+	var buf bytes.Buffer
+	rw := &redactWriter{w: &buf, secret: []byte("SECRET")}
+	for _, b := range []byte("a SECRET, SECRETSECRET & SECRE") {
+		n, err := rw.Write([]byte{b})
+		if n != 1 || err != nil {
+			t.Fatalf("got %d, %v", n, err)
+		}
+	}
+	err := rw.flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a REDACTED, REDACTEDREDACTED & SECRE"; buf.String() != want {
+		t.Errorf("got %q; want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,27 @@
+package pinboard
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+)
+
+// Secret retrieves the user's secret RSS key (for viewing private feeds). As
+// it's available only to the account's owner, it's also a cheap way to check
+// that the Client's token is valid.
+func (c *Client) Secret(ctx context.Context) (string, error) {
+
+	body, err := c.get(ctx, "user/secret", url.Values{})
+	if err != nil {
+		return "", err
+	}
+
+	var rsp struct {
+		Result string `json:"result"`
+	}
+	err = json.Unmarshal(body, &rsp)
+	if err != nil {
+		return "", err
+	}
+	return rsp.Result, nil
+}